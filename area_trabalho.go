@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// prefixoAreaTrabalho identifica os diretórios temporários dos trabalhos
+const prefixoAreaTrabalho = "job-"
+
+// tamanhoMaximoNome limita o nome de arquivo derivado do enviado pelo usuário
+const tamanhoMaximoNome = 100
+
+// dirTrabalhos é onde ficam as áreas de trabalho; definido em init por
+// DIR_TRABALHO
+var dirTrabalhos string
+
+// criarAreaTrabalho cria um diretório exclusivo para os arquivos de um
+// trabalho. Quem cria remove o diretório inteiro ao final.
+func criarAreaTrabalho(id string) (string, error) {
+	if err := os.MkdirAll(dirTrabalhos, 0700); err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de trabalho: %v", err)
+	}
+	area, err := os.MkdirTemp(dirTrabalhos, prefixoAreaTrabalho+nomeArquivoSeguro(id)+"-")
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de trabalho: %v", err)
+	}
+	return area, nil
+}
+
+// removerAreasOrfas apaga as áreas de trabalho deixadas por execuções
+// interrompidas. Só deve rodar na partida, antes de qualquer trabalho.
+func removerAreasOrfas() (int, error) {
+	entradas, err := os.ReadDir(dirTrabalhos)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("erro ao listar %s: %v", dirTrabalhos, err)
+	}
+
+	removidas := 0
+	for _, e := range entradas {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), prefixoAreaTrabalho) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dirTrabalhos, e.Name())); err != nil {
+			return removidas, fmt.Errorf("erro ao remover %s: %v", e.Name(), err)
+		}
+		removidas++
+	}
+	return removidas, nil
+}
+
+// nomeArquivoSeguro reduz o nome enviado pelo usuário a um nome de arquivo
+// simples: sem diretórios, só letras, dígitos, '.', '-' e '_', sem começar
+// com ponto e de tamanho limitado
+func nomeArquivoSeguro(nome string) string {
+	nome = filepath.Base(strings.ReplaceAll(nome, `\`, "/"))
+
+	var sb strings.Builder
+	for _, r := range nome {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	seguro := strings.TrimLeft(sb.String(), "._")
+	if len(seguro) > tamanhoMaximoNome {
+		// Corta no início de um caractere, para não partir um UTF-8 ao meio
+		i := len(seguro) - tamanhoMaximoNome
+		for i < len(seguro) && !utf8.RuneStart(seguro[i]) {
+			i++
+		}
+		seguro = strings.TrimLeft(seguro[i:], "._")
+	}
+	if seguro == "" {
+		return "entrada.sql"
+	}
+	return seguro
+}
+
+// moverArquivo move o arquivo, copiando quando origem e destino ficam em
+// sistemas de arquivos diferentes
+func moverArquivo(origem, destino string) error {
+	if err := os.Rename(origem, destino); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(origem)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := destino + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, destino); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(origem)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNomeArquivoSeguro(t *testing.T) {
+	// 303 bytes: o corte em 100 bytes cai no meio de um "日" (3 bytes)
+	longo := strings.Repeat("日", 99) + "_1.sql"
+
+	casos := []struct {
+		nome     string
+		entrada  string
+		esperado string
+	}{
+		{"simples", "dump.sql", "dump.sql"},
+		{"diretório acima", "../main.go", "main.go"},
+		{"vários diretórios acima", "../../../etc/cron.d/x.sql", "x.sql"},
+		{"caminho absoluto", "/etc/passwd", "passwd"},
+		{"caminho do Windows", `C:\Users\ana\dump.sql`, "dump.sql"},
+		{"diretório acima no Windows", `..\..\main.go`, "main.go"},
+		{"arquivo oculto", ".env", "env"},
+		{"espaços e símbolos", "meu dump (1).sql", "meu_dump__1_.sql"},
+		{"acentos", "relatório março.sql", "relatório_março.sql"},
+		{"longo multibyte", longo, strings.Repeat("日", 31) + "_1.sql"},
+		{"vazio", "", "entrada.sql"},
+		{"só ponto-ponto", "..", "entrada.sql"},
+		{"só barra", "/", "entrada.sql"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			obtido := nomeArquivoSeguro(c.entrada)
+			if obtido != c.esperado {
+				t.Errorf("nomeArquivoSeguro(%q) = %q, esperado %q", c.entrada, obtido, c.esperado)
+			}
+			if !utf8.ValidString(obtido) || len(obtido) > tamanhoMaximoNome {
+				t.Errorf("nome %q inválido ou com %d bytes", obtido, len(obtido))
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Comando conversao converte dumps de painéis pela linha de comando, sem o
+// bot do Telegram nem servidor MySQL.
+//
+// Uso:
+//
+//	conversao convert [--from eclipse] [--to atlas] [-o saida.sql] entrada.sql...
+//	conversao inspect entrada.sql...
+//	conversao validate [--from eclipse] [--to atlas] entrada.sql...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"conversao-db/internal/conversao"
+	"conversao-db/internal/dump"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		uso()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "convert":
+		err = converter(os.Args[2:])
+	case "inspect":
+		err = inspecionar(os.Args[2:])
+	case "validate":
+		err = validar(os.Args[2:])
+	case "help", "-h", "--help":
+		uso()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "subcomando desconhecido: %s\n\n", os.Args[1])
+		uso()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "erro:", err)
+		os.Exit(1)
+	}
+}
+
+func uso() {
+	fmt.Fprintln(os.Stderr, `Uso: conversao <subcomando> [opções] arquivo.sql...
+
+Subcomandos:
+  convert   converte os dumps e grava o SQL do painel de destino
+  inspect   mostra o formato detectado e o resumo de cada dump
+  validate  verifica se os dumps podem ser convertidos sem erros
+
+Use "conversao <subcomando> -h" para ver as opções.`)
+	fmt.Fprintln(os.Stderr, "\nConversores disponíveis:")
+	for _, conv := range conversao.Conversores() {
+		fmt.Fprintf(os.Stderr, "  --from %s --to %s  (%s)\n", conv.Origem(), conv.Destino(), conv.Nome())
+	}
+}
+
+// opcoesConversor registra --from e --to no conjunto de flags
+func opcoesConversor(fs *flag.FlagSet) (*string, *string) {
+	origem := fs.String("from", "", "formato de origem do dump (detectado quando omitido)")
+	destino := fs.String("to", "atlas", "painel de destino")
+	return origem, destino
+}
+
+// escolherConversor usa o conversor informado em --from/--to ou, sem
+// --from, o detectado pelo conteúdo do dump
+func escolherConversor(origem, destino, arquivo string) (conversao.Converter, error) {
+	if origem != "" {
+		conv, ok := conversao.Obter(origem, destino)
+		if !ok {
+			return nil, fmt.Errorf("nenhum conversor de %s para %s", origem, destino)
+		}
+		return conv, nil
+	}
+	conv, err := conversao.DetectarFormato(context.Background(), conversao.Entrada{Arquivo: arquivo})
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, fmt.Errorf("%s: formato não reconhecido, informe --from", arquivo)
+	}
+	if conv.Destino() != destino {
+		return nil, fmt.Errorf("%s: formato detectado %s não converte para %s", arquivo, conv.Nome(), destino)
+	}
+	return conv, nil
+}
+
+func converter(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	origem, destino := opcoesConversor(fs)
+	saida := fs.String("o", "", "arquivo de saída; com vários dumps, um diretório (padrão: <entrada>-convertido.sql)")
+	arquivos := analisarArgumentos(fs, args)
+	if len(arquivos) == 0 {
+		return fmt.Errorf("nenhum arquivo de entrada")
+	}
+
+	falhas := 0
+	usados := make(map[string]bool)
+	for _, arquivo := range arquivos {
+		destinoArquivo := saidaUnica(caminhoSaida(arquivo, *saida, len(arquivos) > 1), usados)
+		if err := converterArquivo(*origem, *destino, arquivo, destinoArquivo); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arquivo, err)
+			falhas++
+			continue
+		}
+		fmt.Printf("%s -> %s\n", arquivo, destinoArquivo)
+	}
+	if falhas > 0 {
+		return fmt.Errorf("%d de %d arquivo(s) não foram convertidos", falhas, len(arquivos))
+	}
+	return nil
+}
+
+// caminhoSaida decide onde gravar a conversão de um arquivo
+func caminhoSaida(arquivo, saida string, varios bool) string {
+	nome := conversao.NomeBase(filepath.Base(arquivo)) + "-convertido.sql"
+	switch {
+	case saida == "":
+		return filepath.Join(filepath.Dir(arquivo), nome)
+	case varios:
+		return filepath.Join(saida, nome)
+	default:
+		return saida
+	}
+}
+
+// saidaUnica evita que entradas com o mesmo nome base (ex.: a.sql e
+// outro/a.sql, ou a.sql e a.sql.gz) gravem no mesmo arquivo de saída,
+// acrescentando -2, -3, ... ao nome repetido
+func saidaUnica(caminho string, usados map[string]bool) string {
+	unico := caminho
+	for i := 2; usados[unico]; i++ {
+		ext := filepath.Ext(caminho)
+		unico = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(caminho, ext), i, ext)
+	}
+	usados[unico] = true
+	return unico
+}
+
+// analisarArgumentos lê as flags em qualquer posição, antes ou depois dos
+// arquivos (flag.Parse para no primeiro argumento que não é flag), e
+// devolve os arquivos. Depois de "--", tudo é arquivo.
+func analisarArgumentos(fs *flag.FlagSet, args []string) []string {
+	var arquivos []string
+	for {
+		fs.Parse(args)
+		resto := fs.Args()
+		if lidos := len(args) - len(resto); lidos > 0 && args[lidos-1] == "--" {
+			return append(arquivos, resto...)
+		}
+		if len(resto) == 0 {
+			return arquivos
+		}
+		arquivos = append(arquivos, resto[0])
+		args = resto[1:]
+	}
+}
+
+func converterArquivo(origem, destino, arquivo, saida string) error {
+	conv, err := escolherConversor(origem, destino, arquivo)
+	if err != nil {
+		return err
+	}
+	dados, err := conv.Ler(context.Background(), conversao.Entrada{Arquivo: arquivo}, nil)
+	if err != nil {
+		return err
+	}
+	if dados, err = conv.Transformar(dados); err != nil {
+		return err
+	}
+	if resumo := dados.Diagnostico().Resumo(); resumo != "" {
+		fmt.Fprintf(os.Stderr, "%s:\n%s", arquivo, resumo)
+	}
+	tabelas, err := conversao.TabelasDe(dados)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(saida); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("erro ao criar diretório de saída: %v", err)
+		}
+	}
+	return dump.EscreverArquivo(saida, tabelas)
+}
+
+func inspecionar(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	arquivos := analisarArgumentos(fs, args)
+
+	if len(arquivos) == 0 {
+		return fmt.Errorf("nenhum arquivo de entrada")
+	}
+	falhas := 0
+	for _, arquivo := range arquivos {
+		fmt.Printf("== %s\n", arquivo)
+		if err := inspecionarArquivo(arquivo); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arquivo, err)
+			falhas++
+		}
+	}
+	if falhas > 0 {
+		return fmt.Errorf("%d de %d arquivo(s) não foram inspecionados", falhas, len(arquivos))
+	}
+	return nil
+}
+
+// inspecionarArquivo mostra o formato detectado e a simulação da conversão
+func inspecionarArquivo(arquivo string) error {
+	conv, err := conversao.DetectarFormato(context.Background(), conversao.Entrada{Arquivo: arquivo})
+	if err != nil {
+		return err
+	}
+	if conv == nil {
+		fmt.Println("Formato não reconhecido.")
+		return nil
+	}
+	fmt.Printf("Formato detectado: %s (--from %s --to %s)\n", conv.Nome(), conv.Origem(), conv.Destino())
+	sim, err := conversao.Simular(context.Background(), conv, conversao.Entrada{Arquivo: arquivo})
+	if err != nil {
+		return err
+	}
+	fmt.Println(sim.Texto())
+	return nil
+}
+
+func validar(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	origem, destino := opcoesConversor(fs)
+	arquivos := analisarArgumentos(fs, args)
+
+	if len(arquivos) == 0 {
+		return fmt.Errorf("nenhum arquivo de entrada")
+	}
+
+	invalidos := 0
+	for _, arquivo := range arquivos {
+		conv, err := validarArquivo(*origem, *destino, arquivo)
+		if err != nil {
+			fmt.Printf("%s: inválido: %v\n", arquivo, err)
+			invalidos++
+			continue
+		}
+		fmt.Printf("%s: ok (%s)\n", arquivo, conv.Nome())
+	}
+	if invalidos > 0 {
+		return fmt.Errorf("%d de %d arquivo(s) inválido(s)", invalidos, len(arquivos))
+	}
+	return nil
+}
+
+// validarArquivo lê e transforma o dump, tratando como erro qualquer
+// registro ou instrução descartada
+func validarArquivo(origem, destino, arquivo string) (conversao.Converter, error) {
+	conv, err := escolherConversor(origem, destino, arquivo)
+	if err != nil {
+		return nil, err
+	}
+	dados, err := conv.Ler(context.Background(), conversao.Entrada{Arquivo: arquivo}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if dados, err = conv.Transformar(dados); err != nil {
+		return nil, err
+	}
+	if rel := dados.Diagnostico(); rel.TotalErros > 0 {
+		return nil, fmt.Errorf("%d problema(s) encontrado(s)\n%s", rel.TotalErros, rel.Resumo())
+	}
+	return conv, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalisarArgumentos(t *testing.T) {
+	casos := []struct {
+		nome     string
+		args     []string
+		arquivos []string
+		origem   string
+		saida    string
+	}{
+		{"flags antes", []string{"--from", "eclipse", "-o", "s.sql", "a.sql"}, []string{"a.sql"}, "eclipse", "s.sql"},
+		{"flags depois", []string{"a.sql", "b.sql", "--from", "eclipse", "-o", "saida"}, []string{"a.sql", "b.sql"}, "eclipse", "saida"},
+		{"flags no meio", []string{"a.sql", "-from=eclipse", "b.sql"}, []string{"a.sql", "b.sql"}, "eclipse", ""},
+		{"-- no início", []string{"--", "-o", "a.sql"}, []string{"-o", "a.sql"}, "", ""},
+		{"-- depois de arquivos", []string{"a.sql", "--from", "eclipse", "--", "--from", "-b.sql"}, []string{"a.sql", "--from", "-b.sql"}, "eclipse", ""},
+		{"sem arquivos", []string{"--from", "eclipse"}, nil, "eclipse", ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			origem, _ := opcoesConversor(fs)
+			saida := fs.String("o", "", "")
+
+			arquivos := analisarArgumentos(fs, c.args)
+			if fmt.Sprint(arquivos) != fmt.Sprint(c.arquivos) {
+				t.Errorf("arquivos = %q, esperado %q", arquivos, c.arquivos)
+			}
+			if *origem != c.origem || *saida != c.saida {
+				t.Errorf("--from %q -o %q, esperado --from %q -o %q", *origem, *saida, c.origem, c.saida)
+			}
+		})
+	}
+}
+
+func TestSaidaUnica(t *testing.T) {
+	usados := make(map[string]bool)
+	casos := []struct{ caminho, esperado string }{
+		{"a-convertido.sql", "a-convertido.sql"},
+		{"a-convertido.sql", "a-convertido-2.sql"},
+		{"outro/a-convertido.sql", "outro/a-convertido.sql"},
+		{"a-convertido.sql", "a-convertido-3.sql"},
+		// Um nome que já coincide com um sufixo gerado também é desviado
+		{"a-convertido-2.sql", "a-convertido-2-2.sql"},
+	}
+	for _, c := range casos {
+		if obtido := saidaUnica(c.caminho, usados); obtido != c.esperado {
+			t.Errorf("saidaUnica(%q) = %q, esperado %q", c.caminho, obtido, c.esperado)
+		}
+	}
+}
+
+func TestCaminhoSaidaSemColisao(t *testing.T) {
+	// Entradas de mesmo nome base em diretórios ou compactações diferentes
+	usados := make(map[string]bool)
+	var saidas []string
+	for _, arquivo := range []string{"a.sql", "outro/a.sql", "a.sql.gz"} {
+		saidas = append(saidas, saidaUnica(caminhoSaida(arquivo, "saida", true), usados))
+	}
+	esperado := []string{
+		filepath.Join("saida", "a-convertido.sql"),
+		filepath.Join("saida", "a-convertido-2.sql"),
+		filepath.Join("saida", "a-convertido-3.sql"),
+	}
+	if fmt.Sprint(saidas) != fmt.Sprint(esperado) {
+		t.Errorf("saídas = %q, esperado %q", saidas, esperado)
+	}
+}
+
+func TestInspecionarContinuaDepoisDeErro(t *testing.T) {
+	dir := t.TempDir()
+	texto := filepath.Join(dir, "leiame.sql")
+	if err := os.WriteFile(texto, []byte("-- não é um dump de painel\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := inspecionar([]string{filepath.Join(dir, "sumiu.sql"), texto, filepath.Join(dir, "tambem.sql")})
+	if err == nil || !strings.Contains(err.Error(), "2 de 3") {
+		t.Errorf("erro = %v, esperado a contagem 2 de 3", err)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tamanhoMaximoEnvio é o maior documento que o bot envia de uma vez. O
+// Telegram aceita até 50 MB; o limite fica um pouco abaixo, com folga.
+const tamanhoMaximoEnvio = 49 << 20
+
+// Compactações aceitas em COMPACTAR_RESULTADO
+const (
+	compactacaoGzip = "gzip"
+	compactacaoZip  = "zip"
+)
+
+// compactacaoResultado é a compactação aplicada ao arquivo convertido antes
+// do envio; vazia envia o SQL como está, salvo se passar de
+// tamanhoMaximoEnvio. Definida em init por COMPACTAR_RESULTADO.
+var compactacaoResultado string
+
+// parteEntrega é um dos arquivos enviados ao usuário
+type parteEntrega struct {
+	Arquivo string
+	Tamanho int64
+	SHA256  string
+}
+
+// entrega é o arquivo convertido pronto para envio: compactado, se for o
+// caso, e dividido em partes quando ainda passa do limite
+type entrega struct {
+	Nome    string // nome do arquivo completo, antes da divisão
+	Tamanho int64
+	SHA256  string
+	Partes  []parteEntrega
+}
+
+// prepararEntrega compacta o arquivo convertido conforme compactacao e o
+// divide em partes numeradas de até limite bytes. Sem compactação
+// configurada, um arquivo acima do limite é compactado com gzip antes de ser
+// dividido. Os arquivos intermediários são removidos; as partes ficam ao lado
+// do arquivo original.
+func prepararEntrega(arquivo, compactacao string, limite int64) (*entrega, error) {
+	info, err := os.Stat(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir o arquivo convertido: %v", err)
+	}
+	if compactacao == "" && info.Size() > limite {
+		compactacao = compactacaoGzip
+	}
+	if compactacao != "" {
+		compactado, err := compactarArquivo(arquivo, compactacao)
+		if err != nil {
+			return nil, err
+		}
+		os.Remove(arquivo)
+		arquivo = compactado
+	}
+
+	tamanho, soma, err := resumoArquivo(arquivo)
+	if err != nil {
+		return nil, err
+	}
+	e := &entrega{Nome: filepath.Base(arquivo), Tamanho: tamanho, SHA256: soma}
+	if tamanho <= limite {
+		e.Partes = []parteEntrega{{Arquivo: arquivo, Tamanho: tamanho, SHA256: soma}}
+		return e, nil
+	}
+
+	if e.Partes, err = dividirArquivo(arquivo, limite); err != nil {
+		return nil, err
+	}
+	os.Remove(arquivo)
+	return e, nil
+}
+
+// compactarArquivo grava ao lado de arquivo a versão compactada com gzip ou
+// zip e devolve o caminho dela
+func compactarArquivo(arquivo, compactacao string) (string, error) {
+	in, err := os.Open(arquivo)
+	if err != nil {
+		return "", fmt.Errorf("erro ao abrir o arquivo convertido: %v", err)
+	}
+	defer in.Close()
+
+	var destino string
+	switch compactacao {
+	case compactacaoGzip:
+		destino = arquivo + ".gz"
+	case compactacaoZip:
+		destino = strings.TrimSuffix(arquivo, filepath.Ext(arquivo)) + ".zip"
+	default:
+		return "", fmt.Errorf("compactação desconhecida: %s", compactacao)
+	}
+
+	out, err := os.Create(destino)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar o arquivo compactado: %v", err)
+	}
+
+	switch compactacao {
+	case compactacaoGzip:
+		gz := gzip.NewWriter(out)
+		gz.Name = filepath.Base(arquivo)
+		if _, err = io.Copy(gz, in); err == nil {
+			err = gz.Close()
+		}
+	case compactacaoZip:
+		z := zip.NewWriter(out)
+		var w io.Writer
+		if w, err = z.Create(filepath.Base(arquivo)); err == nil {
+			if _, err = io.Copy(w, in); err == nil {
+				err = z.Close()
+			}
+		}
+	}
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(destino)
+		return "", fmt.Errorf("erro ao compactar o arquivo convertido: %v", err)
+	}
+	return destino, nil
+}
+
+// dividirArquivo copia arquivo em partes de até limite bytes, numeradas a
+// partir de 001 (arquivo.001, arquivo.002, ...)
+func dividirArquivo(arquivo string, limite int64) ([]parteEntrega, error) {
+	in, err := os.Open(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir o arquivo convertido: %v", err)
+	}
+	defer in.Close()
+
+	var partes []parteEntrega
+	for {
+		nome := fmt.Sprintf("%s.%03d", arquivo, len(partes)+1)
+		out, err := os.Create(nome)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao criar a parte %s: %v", filepath.Base(nome), err)
+		}
+		h := sha256.New()
+		n, err := io.CopyN(io.MultiWriter(out, h), in, limite)
+		errClose := out.Close()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("erro ao gravar a parte %s: %v", filepath.Base(nome), err)
+		}
+		if errClose != nil {
+			return nil, fmt.Errorf("erro ao gravar a parte %s: %v", filepath.Base(nome), errClose)
+		}
+		if n == 0 {
+			// O arquivo terminou exatamente no fim da parte anterior
+			os.Remove(nome)
+			return partes, nil
+		}
+		partes = append(partes, parteEntrega{Arquivo: nome, Tamanho: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+		if err == io.EOF {
+			return partes, nil
+		}
+	}
+}
+
+// resumoArquivo devolve o tamanho e o SHA-256 do arquivo
+func resumoArquivo(arquivo string) (int64, string, error) {
+	f, err := os.Open(arquivo)
+	if err != nil {
+		return 0, "", fmt.Errorf("erro ao abrir o arquivo convertido: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("erro ao ler o arquivo convertido: %v", err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remover apaga as partes enviadas
+func (e *entrega) remover() {
+	for _, p := range e.Partes {
+		os.Remove(p.Arquivo)
+	}
+}
+
+// gravarSomas grava, ao lado das partes, o arquivo <Nome>.sha256 no formato
+// do sha256sum, com o SHA-256 do arquivo completo e o de cada parte, e
+// devolve o caminho dele
+func (e *entrega) gravarSomas() (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s  %s\n", e.SHA256, e.Nome)
+	if len(e.Partes) > 1 {
+		for _, p := range e.Partes {
+			fmt.Fprintf(&sb, "%s  %s\n", p.SHA256, filepath.Base(p.Arquivo))
+		}
+	}
+	caminho := filepath.Join(filepath.Dir(e.Partes[0].Arquivo), e.Nome+".sha256")
+	if err := os.WriteFile(caminho, []byte(sb.String()), 0644); err != nil {
+		return "", fmt.Errorf("erro ao gravar os SHA-256: %v", err)
+	}
+	return caminho, nil
+}
+
+// manifesto descreve o que foi enviado e, quando há partes, como juntá-las.
+// Os SHA-256 de cada parte vão no arquivo de gravarSomas.
+func (e *entrega) manifesto() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Arquivo: %s (%s)\nSHA-256: %s\n", e.Nome, formatarTamanho(e.Tamanho), e.SHA256)
+	if len(e.Partes) == 1 {
+		return sb.String()
+	}
+
+	nomes := make([]string, len(e.Partes))
+	for i, p := range e.Partes {
+		nomes[i] = filepath.Base(p.Arquivo)
+	}
+	fmt.Fprintf(&sb, "\nEnviado em %d partes. Para juntá-las:\n", len(e.Partes))
+	fmt.Fprintf(&sb, "Linux/macOS: cat %s > %s\n", strings.Join(nomes, " "), e.Nome)
+	fmt.Fprintf(&sb, "Windows: copy /b %s %s\n", strings.Join(nomes, "+"), e.Nome)
+	fmt.Fprintf(&sb, "Depois confira com: sha256sum -c --ignore-missing %s.sha256", e.Nome)
+	return sb.String()
+}
+
+// formatarTamanho mostra bytes em KB ou MB
+func formatarTamanho(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
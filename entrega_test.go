@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// arquivoAleatorio grava tamanho bytes aleatórios, que não se compactam
+func arquivoAleatorio(t *testing.T, nome string, tamanho int) (string, []byte) {
+	t.Helper()
+	conteudo := make([]byte, tamanho)
+	rand.Read(conteudo)
+	caminho := filepath.Join(t.TempDir(), nome)
+	if err := os.WriteFile(caminho, conteudo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return caminho, conteudo
+}
+
+func somaSHA256(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// juntarPartes concatena as partes e confere o tamanho e o SHA-256 de cada uma
+func juntarPartes(t *testing.T, partes []parteEntrega) []byte {
+	t.Helper()
+	var junto bytes.Buffer
+	for _, p := range partes {
+		conteudo, err := os.ReadFile(p.Arquivo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(conteudo)) != p.Tamanho || somaSHA256(conteudo) != p.SHA256 {
+			t.Errorf("parte %s: tamanho ou SHA-256 não conferem", filepath.Base(p.Arquivo))
+		}
+		junto.Write(conteudo)
+	}
+	return junto.Bytes()
+}
+
+func TestDividirArquivo(t *testing.T) {
+	casos := []struct {
+		nome     string
+		tamanho  int
+		limite   int64
+		esperado []int64
+	}{
+		{"resto na última parte", 25, 10, []int64{10, 10, 5}},
+		{"múltiplo exato do limite", 20, 10, []int64{10, 10}},
+		{"um byte além do limite", 11, 10, []int64{10, 1}},
+		{"cabe numa parte", 7, 10, []int64{7}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			arquivo, conteudo := arquivoAleatorio(t, "dump.sql.gz", c.tamanho)
+			partes, err := dividirArquivo(arquivo, c.limite)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var tamanhos []int64
+			for i, p := range partes {
+				tamanhos = append(tamanhos, p.Tamanho)
+				if nome := fmt.Sprintf("dump.sql.gz.%03d", i+1); filepath.Base(p.Arquivo) != nome {
+					t.Errorf("parte %d chamada %s, esperado %s", i+1, filepath.Base(p.Arquivo), nome)
+				}
+			}
+			if fmt.Sprint(tamanhos) != fmt.Sprint(c.esperado) {
+				t.Errorf("tamanhos das partes = %v, esperado %v", tamanhos, c.esperado)
+			}
+			if !bytes.Equal(juntarPartes(t, partes), conteudo) {
+				t.Error("as partes juntas não reproduzem o arquivo")
+			}
+			// Nenhuma parte vazia sobra no diretório
+			entradas, _ := os.ReadDir(filepath.Dir(arquivo))
+			if len(entradas) != len(partes)+1 {
+				t.Errorf("%d arquivos no diretório, esperado %d", len(entradas), len(partes)+1)
+			}
+		})
+	}
+}
+
+func TestDividirArquivoLimiteDeEnvio(t *testing.T) {
+	// Arquivo esparso: só os tamanhos importam aqui
+	arquivo := filepath.Join(t.TempDir(), "dump.sql.gz")
+	f, err := os.Create(arquivo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(2*tamanhoMaximoEnvio + 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	partes, err := dividirArquivo(arquivo, tamanhoMaximoEnvio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperado := []parteEntrega{
+		{Arquivo: arquivo + ".001", Tamanho: tamanhoMaximoEnvio},
+		{Arquivo: arquivo + ".002", Tamanho: tamanhoMaximoEnvio},
+		{Arquivo: arquivo + ".003", Tamanho: 1},
+	}
+	if len(partes) != len(esperado) {
+		t.Fatalf("%d partes, esperado %d", len(partes), len(esperado))
+	}
+	for i, p := range partes {
+		if p.Arquivo != esperado[i].Arquivo || p.Tamanho != esperado[i].Tamanho {
+			t.Errorf("parte %d = %s (%d bytes), esperado %s (%d bytes)", i+1, p.Arquivo, p.Tamanho, esperado[i].Arquivo, esperado[i].Tamanho)
+		}
+	}
+}
+
+func TestPrepararEntrega(t *testing.T) {
+	casos := []struct {
+		nome        string
+		compactacao string
+		tamanho     int
+		limite      int64
+		arquivo     string // nome do arquivo completo
+		partes      int
+	}{
+		{"pequeno vai como está", "", 500, 1000, "dump.sql", 1},
+		{"grande é compactado e dividido", "", 2500, 1000, "dump.sql.gz", 3},
+		{"gzip configurado", compactacaoGzip, 500, 1000, "dump.sql.gz", 1},
+		{"zip configurado e dividido", compactacaoZip, 2500, 1000, "dump.zip", 3},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			arquivo, _ := arquivoAleatorio(t, "dump.sql", c.tamanho)
+			e, err := prepararEntrega(arquivo, c.compactacao, c.limite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Nome != c.arquivo || len(e.Partes) != c.partes {
+				t.Fatalf("entrega de %s em %d partes, esperado %s em %d", e.Nome, len(e.Partes), c.arquivo, c.partes)
+			}
+			for _, p := range e.Partes {
+				if p.Tamanho > c.limite {
+					t.Errorf("parte %s com %d bytes, acima do limite", filepath.Base(p.Arquivo), p.Tamanho)
+				}
+			}
+
+			junto := juntarPartes(t, e.Partes)
+			if int64(len(junto)) != e.Tamanho || somaSHA256(junto) != e.SHA256 {
+				t.Error("tamanho ou SHA-256 do arquivo completo não conferem com as partes")
+			}
+
+			// O .sha256 segue o formato do sha256sum e confere com as partes
+			somas, err := e.gravarSomas()
+			if err != nil {
+				t.Fatal(err)
+			}
+			conteudo, err := os.ReadFile(somas)
+			if err != nil {
+				t.Fatal(err)
+			}
+			esperado := fmt.Sprintf("%s  %s\n", somaSHA256(junto), e.Nome)
+			if len(e.Partes) > 1 {
+				for _, p := range e.Partes {
+					parte, _ := os.ReadFile(p.Arquivo)
+					esperado += fmt.Sprintf("%s  %s\n", somaSHA256(parte), filepath.Base(p.Arquivo))
+				}
+			}
+			if string(conteudo) != esperado {
+				t.Errorf("%s:\n%s\nesperado:\n%s", filepath.Base(somas), conteudo, esperado)
+			}
+
+			// Só as partes e o .sha256 ficam no diretório
+			e.remover()
+			os.Remove(somas)
+			if entradas, _ := os.ReadDir(filepath.Dir(arquivo)); len(entradas) != 0 {
+				var nomes []string
+				for _, en := range entradas {
+					nomes = append(nomes, en.Name())
+				}
+				t.Errorf("arquivos intermediários deixados: %s", strings.Join(nomes, ", "))
+			}
+		})
+	}
+}
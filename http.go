@@ -0,0 +1,440 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"conversao-db/internal/conversao"
+)
+
+// validadeResultado é por quanto tempo um trabalho encerrado, com o arquivo
+// convertido, fica disponível para consulta e download
+const validadeResultado = 24 * time.Hour
+
+// Situações de um trabalho enviado pela API
+const (
+	statusNaFila      = "na_fila"
+	statusProcessando = "processando"
+	statusConcluido   = "concluido"
+	statusFalhou      = "falhou"
+)
+
+// jobAPI é a situação de um trabalho enviado pela API, como devolvida em
+// GET /jobs/{id}
+type jobAPI struct {
+	ID        string     `json:"id"`
+	Arquivo   string     `json:"arquivo"`
+	Conversor string     `json:"conversor,omitempty"`
+	Simular   bool       `json:"simular"`
+	Status    string     `json:"status"`
+	Posicao   int        `json:"posicao,omitempty"` // posição na fila, enquanto aguarda
+	Etapa     string     `json:"etapa,omitempty"`
+	Progresso string     `json:"progresso,omitempty"`
+	Mensagens []string   `json:"mensagens"`
+	Simulacao string     `json:"simulacao,omitempty"`
+	Membro    string     `json:"membro,omitempty"` // dump escolhido num pacote zip ou tar
+	Dumps     []string   `json:"dumps,omitempty"`  // dumps do pacote, quando é preciso escolher
+	Erro      string     `json:"erro,omitempty"`
+	Criado    time.Time  `json:"criado"`
+	Concluido *time.Time `json:"concluido,omitempty"`
+
+	resultado string // arquivo convertido, pronto para GET /jobs/{id}/result
+}
+
+// servidorAPI expõe a fila de conversão por HTTP. Os trabalhos enviados
+// passam pela mesma WorkQueue do bot; a situação de cada um fica em memória,
+// e só os ainda pendentes são retomados após um reinício.
+type servidorAPI struct {
+	fila          *WorkQueue
+	token         string // token exigido no cabeçalho Authorization
+	dirUploads    string
+	dirResultados string
+
+	mu   sync.Mutex
+	jobs map[string]*jobAPI
+}
+
+func novoServidorAPI(fila *WorkQueue, token string) *servidorAPI {
+	return &servidorAPI{
+		fila:          fila,
+		token:         token,
+		dirUploads:    "uploads",
+		dirResultados: "resultados",
+		jobs:          make(map[string]*jobAPI),
+	}
+}
+
+// Handler devolve as rotas da API
+func (s *servidorAPI) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.autenticado(s.criarJob))
+	mux.HandleFunc("GET /jobs/{id}", s.autenticado(s.consultarJob))
+	mux.HandleFunc("GET /jobs/{id}/result", s.autenticado(s.resultadoJob))
+	return mux
+}
+
+func (s *servidorAPI) autenticado(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recebido := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(recebido, []byte("Bearer "+s.token)) != 1 {
+			responderErro(w, http.StatusUnauthorized, "token inválido")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// criarJob recebe o dump em multipart (campo "arquivo") e, opcionalmente, os
+// campos "origem", "destino" e "simular", e coloca o trabalho na fila
+func (s *servidorAPI) criarJob(w http.ResponseWriter, r *http.Request) {
+	// O corpo pode passar um pouco do arquivo por causa do multipart
+	r.Body = http.MaxBytesReader(w, r.Body, tamanhoMaximoArquivo+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		responderErro(w, http.StatusBadRequest, fmt.Sprintf("formulário inválido: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	arquivo, cabecalho, err := r.FormFile("arquivo")
+	if err != nil {
+		responderErro(w, http.StatusBadRequest, "campo arquivo ausente")
+		return
+	}
+	defer arquivo.Close()
+
+	var chave string
+	if origem := r.FormValue("origem"); origem != "" {
+		destino := r.FormValue("destino")
+		if destino == "" {
+			destino = "atlas"
+		}
+		conv, ok := conversao.Obter(origem, destino)
+		if !ok {
+			responderErro(w, http.StatusBadRequest, fmt.Sprintf("nenhum conversor de %s para %s", origem, destino))
+			return
+		}
+		chave = conversao.ChaveDe(conv)
+	}
+
+	var simular bool
+	if v := r.FormValue("simular"); v != "" {
+		if simular, err = strconv.ParseBool(v); err != nil {
+			responderErro(w, http.StatusBadRequest, "valor inválido para simular")
+			return
+		}
+	}
+
+	membro := r.FormValue("membro")
+
+	id, err := novoIDJob()
+	if err != nil {
+		responderErro(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := os.MkdirAll(s.dirUploads, 0755); err != nil {
+		responderErro(w, http.StatusInternalServerError, fmt.Sprintf("erro ao criar diretório de uploads: %v", err))
+		return
+	}
+	inputFile := filepath.Join(s.dirUploads, id)
+	if err := salvarUpload(arquivo, inputFile); err != nil {
+		responderErro(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	job := &jobAPI{
+		ID:        id,
+		Arquivo:   filepath.Base(cabecalho.Filename),
+		Conversor: chave,
+		Simular:   simular,
+		Membro:    membro,
+		Status:    statusNaFila,
+		Mensagens: []string{},
+		Criado:    time.Now(),
+	}
+	s.mu.Lock()
+	s.jobs[id] = job
+	s.mu.Unlock()
+
+	posicao, err := s.fila.AddJob(ConversionJob{
+		ID:        id,
+		Canal:     canalHTTP,
+		FileName:  job.Arquivo,
+		InputFile: inputFile,
+		Membro:    membro,
+		Conversor: chave,
+		Simular:   simular,
+		Cliente:   clienteDe(r),
+	})
+	if err != nil {
+		s.mu.Lock()
+		delete(s.jobs, id)
+		s.mu.Unlock()
+		os.Remove(inputFile)
+		responderErro(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	resposta := *job
+	s.mu.Unlock()
+	if resposta.Status == statusNaFila {
+		resposta.Posicao = posicao
+	}
+
+	w.Header().Set("Location", "/jobs/"+id)
+	responderJSON(w, http.StatusAccepted, resposta)
+}
+
+func (s *servidorAPI) consultarJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var resposta jobAPI
+	if ok {
+		resposta = *job
+		resposta.Mensagens = append([]string(nil), job.Mensagens...)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		responderErro(w, http.StatusNotFound, "trabalho não encontrado")
+		return
+	}
+	if resposta.Status == statusNaFila {
+		resposta.Posicao = s.fila.Posicao(resposta.ID)
+	}
+	responderJSON(w, http.StatusOK, resposta)
+}
+
+func (s *servidorAPI) resultadoJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var status, resultado, nome string
+	if ok {
+		status, resultado = job.Status, job.resultado
+		nome = conversao.NomeBase(job.Arquivo) + "-convertido.sql"
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		responderErro(w, http.StatusNotFound, "trabalho não encontrado")
+	case status != statusConcluido:
+		responderErro(w, http.StatusConflict, fmt.Sprintf("trabalho %s", status))
+	case resultado == "":
+		responderErro(w, http.StatusNotFound, "trabalho sem arquivo convertido")
+	default:
+		w.Header().Set("Content-Type", "application/sql")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", nome))
+		http.ServeFile(w, r, resultado)
+	}
+}
+
+// restaurar volta a acompanhar um trabalho da API que ficou na fila de uma
+// execução anterior
+func (s *servidorAPI) restaurar(job ConversionJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = &jobAPI{
+		ID:        job.ID,
+		Arquivo:   job.FileName,
+		Conversor: job.Conversor,
+		Simular:   job.Simular,
+		Membro:    job.Membro,
+		Status:    statusNaFila,
+		Mensagens: []string{},
+		Criado:    time.Now(),
+	}
+}
+
+// removerArquivosOrfaos apaga, ao iniciar, os resultados de execuções
+// anteriores, cuja situação se perdeu com o reinício, e os uploads que não
+// pertencem a nenhum trabalho retomado da fila. Deve ser chamada depois de
+// restaurar.
+func (s *servidorAPI) removerArquivosOrfaos() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removidos := 0
+	for _, dir := range []string{s.dirResultados, s.dirUploads} {
+		entradas, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return removidos, fmt.Errorf("erro ao listar %s: %v", dir, err)
+		}
+		for _, e := range entradas {
+			if _, pendente := s.jobs[e.Name()]; dir == s.dirUploads && pendente {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+				return removidos, fmt.Errorf("erro ao remover %s: %v", e.Name(), err)
+			}
+			removidos++
+		}
+	}
+	return removidos, nil
+}
+
+// limparExpirados esquece os trabalhos encerrados há mais de validade e
+// apaga os arquivos convertidos deles
+func (s *servidorAPI) limparExpirados(validade time.Duration) {
+	limite := time.Now().Add(-validade)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, job := range s.jobs {
+		if job.Concluido == nil || job.Concluido.After(limite) {
+			continue
+		}
+		if job.resultado != "" {
+			if err := os.Remove(job.resultado); err != nil && !os.IsNotExist(err) {
+				log.Printf("Erro ao remover o resultado do trabalho %s: %v", id, err)
+				continue
+			}
+		}
+		delete(s.jobs, id)
+	}
+}
+
+// limparPeriodicamente chama limparExpirados de tempos em tempos, até o fim
+// do programa
+func (s *servidorAPI) limparPeriodicamente(validade time.Duration) {
+	for range time.Tick(validade / 24) {
+		s.limparExpirados(validade)
+	}
+}
+
+// atualizar altera a situação de um trabalho sob o mutex
+func (s *servidorAPI) atualizar(id string, fn func(job *jobAPI)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// notificador devolve o Notificador que registra o andamento do trabalho id
+func (s *servidorAPI) notificador(id string) Notificador {
+	return &notificadorHTTP{api: s, id: id}
+}
+
+// notificadorHTTP registra as mensagens e o resultado na situação do
+// trabalho, consultada pelo cliente em GET /jobs/{id}
+type notificadorHTTP struct {
+	api *servidorAPI
+	id  string
+}
+
+func (n *notificadorHTTP) Iniciado() {
+	n.api.atualizar(n.id, func(job *jobAPI) {
+		job.Status = statusProcessando
+	})
+}
+
+func (n *notificadorHTTP) Progresso(etapa, detalhe string) {
+	n.api.atualizar(n.id, func(job *jobAPI) {
+		job.Etapa = etapa
+		job.Progresso = detalhe
+	})
+}
+
+func (n *notificadorHTTP) Mensagem(texto string) {
+	n.api.atualizar(n.id, func(job *jobAPI) {
+		job.Mensagens = append(job.Mensagens, texto)
+	})
+}
+
+func (n *notificadorHTTP) Falha(texto string) {
+	n.encerrar(statusFalhou, func(job *jobAPI) {
+		job.Erro = texto
+	})
+}
+
+// Simulacao conclui o trabalho com o relatório; para converter de fato, o
+// cliente envia o arquivo de novo sem simular
+func (n *notificadorHTTP) Simulacao(job ConversionJob, texto string) {
+	n.encerrar(statusConcluido, func(job *jobAPI) {
+		job.Simulacao = texto
+	})
+}
+
+// EscolherDump encerra o trabalho com a lista de dumps do pacote; o cliente
+// envia o arquivo de novo com o campo membro
+func (n *notificadorHTTP) EscolherDump(job ConversionJob, dumps []string) {
+	n.encerrar(statusFalhou, func(job *jobAPI) {
+		job.Erro = "o arquivo tem mais de um dump SQL; envie-o novamente com o campo membro indicando qual converter"
+		job.Dumps = dumps
+	})
+}
+
+// Resultado move o arquivo convertido para o diretório de resultados
+func (n *notificadorHTTP) Resultado(arquivo string) error {
+	if err := os.MkdirAll(n.api.dirResultados, 0755); err != nil {
+		err = fmt.Errorf("erro ao criar diretório de resultados: %v", err)
+		n.Falha(err.Error())
+		return err
+	}
+	destino := filepath.Join(n.api.dirResultados, n.id+".sql")
+	if err := moverArquivo(arquivo, destino); err != nil {
+		err = fmt.Errorf("erro ao guardar o arquivo convertido: %v", err)
+		n.Falha(err.Error())
+		return err
+	}
+	n.encerrar(statusConcluido, func(job *jobAPI) {
+		job.resultado = destino
+	})
+	return nil
+}
+
+func (n *notificadorHTTP) encerrar(status string, fn func(job *jobAPI)) {
+	agora := time.Now()
+	n.api.atualizar(n.id, func(job *jobAPI) {
+		fn(job)
+		job.Status = status
+		job.Concluido = &agora
+	})
+}
+
+// clienteDe identifica quem fez a requisição, pelo IP de origem
+func clienteDe(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func salvarUpload(r io.Reader, caminho string) error {
+	out, err := os.Create(caminho)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar o arquivo: %v", err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(caminho)
+		return fmt.Errorf("erro ao salvar o arquivo: %v", err)
+	}
+	return out.Close()
+}
+
+func responderJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Erro ao escrever resposta: %v", err)
+	}
+}
+
+func responderErro(w http.ResponseWriter, status int, mensagem string) {
+	responderJSON(w, status, map[string]string{"erro": mensagem})
+}
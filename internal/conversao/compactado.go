@@ -0,0 +1,351 @@
+package conversao
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Entrada identifica o dump a ler: um arquivo SQL, puro ou compactado com
+// gzip, ou um dos dumps de um pacote zip ou tar (compactado ou não)
+type Entrada struct {
+	Arquivo string
+	Membro  string // nome do dump dentro do pacote; vazio quando há um só
+	// LimiteDescompactado é quantos bytes o conteúdo descompactado pode ter;
+	// 0 não limita. Protege contra arquivos pequenos que se expandem em
+	// volumes enormes ao descompactar.
+	LimiteDescompactado int64
+}
+
+// Dump é um dump SQL encontrado num arquivo
+type Dump struct {
+	Nome    string // nome dentro do pacote; vazio fora de zip e tar
+	Tamanho int64  // tamanho descompactado; 0 se desconhecido
+}
+
+// ErrVariosDumps indica um pacote com mais de um dump SQL sem Entrada.Membro
+var ErrVariosDumps = errors.New("o arquivo tem mais de um dump SQL")
+
+// ErrDescompactadoGrande indica um arquivo que, descompactado, passa de
+// Entrada.LimiteDescompactado
+var ErrDescompactadoGrande = errors.New("arquivo descompactado maior que o tamanho máximo permitido")
+
+// formatoArquivo é o tipo do arquivo enviado, identificado pelos primeiros
+// bytes e não pela extensão
+type formatoArquivo int
+
+const (
+	arquivoSQL formatoArquivo = iota
+	arquivoGzip
+	arquivoZip
+	arquivoTar
+	arquivoTarGzip
+)
+
+// tamanhoCabecalhoTar cobre a assinatura "ustar", no offset 257
+const tamanhoCabecalhoTar = 262
+
+// identificarArquivo lê o início do arquivo (e, se for gzip, o início do
+// conteúdo descompactado) para saber como abri-lo
+func identificarArquivo(arquivo string) (formatoArquivo, error) {
+	file, err := os.Open(arquivo)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao abrir arquivo: %v", err)
+	}
+	defer file.Close()
+
+	cabecalho := make([]byte, tamanhoCabecalhoTar)
+	n, err := io.ReadFull(file, cabecalho)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, fmt.Errorf("erro ao ler arquivo: %v", err)
+	}
+	cabecalho = cabecalho[:n]
+
+	switch {
+	case bytes.HasPrefix(cabecalho, []byte{0x1f, 0x8b}):
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("erro ao ler arquivo: %v", err)
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, fmt.Errorf("erro ao descompactar arquivo: %v", err)
+		}
+		defer gz.Close()
+		conteudo := make([]byte, tamanhoCabecalhoTar)
+		n, err := io.ReadFull(gz, conteudo)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, fmt.Errorf("erro ao descompactar arquivo: %v", err)
+		}
+		if ehTar(conteudo[:n]) {
+			return arquivoTarGzip, nil
+		}
+		return arquivoGzip, nil
+	case bytes.HasPrefix(cabecalho, []byte("PK\x03\x04")), bytes.HasPrefix(cabecalho, []byte("PK\x05\x06")):
+		return arquivoZip, nil
+	case ehTar(cabecalho):
+		return arquivoTar, nil
+	}
+	return arquivoSQL, nil
+}
+
+func ehTar(cabecalho []byte) bool {
+	return len(cabecalho) >= tamanhoCabecalhoTar && string(cabecalho[257:262]) == "ustar"
+}
+
+// ehDumpSQL informa se um arquivo dentro de um pacote é um dump a
+// considerar. Arquivos ocultos (como os "._" e "__MACOSX" do macOS) ficam
+// de fora.
+func ehDumpSQL(nome string) bool {
+	if strings.HasPrefix(nome, "__MACOSX/") || strings.HasPrefix(path.Base(nome), ".") {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(nome), ".sql")
+}
+
+// ListarDumps lista os dumps SQL do arquivo de e; e.Membro é ignorado. Um
+// arquivo SQL ou .sql.gz é um único dump, de nome vazio; pacotes zip e tar
+// podem ter vários, ou nenhum.
+func ListarDumps(e Entrada) ([]Dump, error) {
+	arquivo := e.Arquivo
+	formato, err := identificarArquivo(arquivo)
+	if err != nil {
+		return nil, err
+	}
+
+	switch formato {
+	case arquivoSQL:
+		info, err := os.Stat(arquivo)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo: %v", err)
+		}
+		return []Dump{{Tamanho: info.Size()}}, nil
+	case arquivoGzip:
+		return []Dump{{Tamanho: tamanhoGzip(arquivo)}}, nil
+	case arquivoZip:
+		z, err := zip.OpenReader(arquivo)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo zip: %v", err)
+		}
+		defer z.Close()
+		var dumps []Dump
+		for _, f := range z.File {
+			if !f.FileInfo().IsDir() && ehDumpSQL(f.Name) {
+				dumps = append(dumps, Dump{Nome: f.Name, Tamanho: int64(f.UncompressedSize64)})
+			}
+		}
+		return dumps, nil
+	}
+
+	// tar: os cabeçalhos só são encontrados percorrendo o pacote inteiro
+	tr, fechar, err := abrirTar(arquivo, formato, e.LimiteDescompactado)
+	if err != nil {
+		return nil, err
+	}
+	defer fechar()
+	var dumps []Dump
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return dumps, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler arquivo tar: %v", err)
+		}
+		if h.Typeflag == tar.TypeReg && ehDumpSQL(h.Name) {
+			dumps = append(dumps, Dump{Nome: h.Name, Tamanho: h.Size})
+		}
+	}
+}
+
+// tamanhoGzip lê o tamanho descompactado gravado no fim de um arquivo gzip.
+// O campo guarda o tamanho módulo 2^32, então serve só para estimativas.
+func tamanhoGzip(arquivo string) int64 {
+	file, err := os.Open(arquivo)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	fim := make([]byte, 4)
+	if info, err := file.Stat(); err != nil || info.Size() < 4 {
+		return 0
+	} else if _, err := file.ReadAt(fim, info.Size()-4); err != nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint32(fim))
+}
+
+// AbrirEntrada abre o dump para leitura. Arquivos compactados são
+// descompactados em fluxo, à medida que o dump é lido, sem gravar nada em
+// disco. Num pacote com vários dumps, Membro precisa indicar qual ler
+// (ErrVariosDumps, senão).
+func AbrirEntrada(e Entrada) (io.ReadCloser, error) {
+	formato, err := identificarArquivo(e.Arquivo)
+	if err != nil {
+		return nil, err
+	}
+
+	switch formato {
+	case arquivoSQL:
+		file, err := os.Open(e.Arquivo)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo: %v", err)
+		}
+		return file, nil
+	case arquivoGzip:
+		file, err := os.Open(e.Arquivo)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo: %v", err)
+		}
+		gz, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("erro ao descompactar arquivo: %v", err)
+		}
+		return leitorCompactado{limitar(gz, e.LimiteDescompactado), func() { gz.Close(); file.Close() }}, nil
+	}
+
+	membro := e.Membro
+	if membro == "" {
+		dumps, err := ListarDumps(e)
+		if err != nil {
+			return nil, err
+		}
+		switch len(dumps) {
+		case 0:
+			return nil, fmt.Errorf("nenhum dump .sql encontrado no arquivo")
+		case 1:
+			membro = dumps[0].Nome
+		default:
+			return nil, ErrVariosDumps
+		}
+	}
+
+	if formato == arquivoZip {
+		z, err := zip.OpenReader(e.Arquivo)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo zip: %v", err)
+		}
+		for _, f := range z.File {
+			if f.Name != membro {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				z.Close()
+				return nil, fmt.Errorf("erro ao descompactar %s: %v", membro, err)
+			}
+			return leitorCompactado{limitar(r, e.LimiteDescompactado), func() { r.Close(); z.Close() }}, nil
+		}
+		z.Close()
+		return nil, fmt.Errorf("dump %s não encontrado no arquivo", membro)
+	}
+
+	tr, fechar, err := abrirTar(e.Arquivo, formato, e.LimiteDescompactado)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			fechar()
+			return nil, fmt.Errorf("dump %s não encontrado no arquivo", membro)
+		}
+		if err != nil {
+			fechar()
+			return nil, fmt.Errorf("erro ao ler arquivo tar: %v", err)
+		}
+		if h.Typeflag == tar.TypeReg && h.Name == membro {
+			return leitorCompactado{tr, fechar}, nil
+		}
+	}
+}
+
+// abrirTar abre um pacote tar, compactado com gzip ou não. limite vale para
+// o tar descompactado.
+func abrirTar(arquivo string, formato formatoArquivo, limite int64) (*tar.Reader, func(), error) {
+	file, err := os.Open(arquivo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao abrir arquivo: %v", err)
+	}
+	if formato != arquivoTarGzip {
+		return tar.NewReader(bufio.NewReader(file)), func() { file.Close() }, nil
+	}
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("erro ao descompactar arquivo: %v", err)
+	}
+	return tar.NewReader(limitar(gz, limite)), func() { gz.Close(); file.Close() }, nil
+}
+
+// limitar devolve um leitor que falha com ErrDescompactadoGrande quando r
+// passa de limite bytes; com limite 0, devolve o próprio r
+func limitar(r io.Reader, limite int64) io.Reader {
+	if limite <= 0 {
+		return r
+	}
+	return &leitorLimitado{r: r, restam: limite}
+}
+
+type leitorLimitado struct {
+	r      io.Reader
+	restam int64
+}
+
+func (l *leitorLimitado) Read(p []byte) (int, error) {
+	// Lê um byte além do limite para saber se o conteúdo passa dele
+	if int64(len(p)) > l.restam+1 {
+		p = p[:l.restam+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.restam {
+		n, l.restam = int(l.restam), 0
+		return n, ErrDescompactadoGrande
+	}
+	l.restam -= int64(n)
+	return n, err
+}
+
+// leitorCompactado lê o dump descompactado e, ao fechar, libera também os
+// leitores e o arquivo por baixo dele
+type leitorCompactado struct {
+	io.Reader
+	fechar func()
+}
+
+func (l leitorCompactado) Close() error {
+	l.fechar()
+	return nil
+}
+
+// extensoesDump são as extensões de dumps, compactados ou não
+var extensoesDump = []string{".gz", ".tgz", ".zip", ".tar", ".sql"}
+
+// NomeBase tira do nome de um arquivo de dump as extensões de SQL e de
+// compactação, ex.: "painel.sql.gz" vira "painel"
+func NomeBase(nome string) string {
+	for {
+		ext := strings.ToLower(path.Ext(nome))
+		removida := false
+		for _, e := range extensoesDump {
+			if ext == e && len(nome) > len(ext) {
+				nome = nome[:len(nome)-len(ext)]
+				removida = true
+				break
+			}
+		}
+		if !removida {
+			return nome
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package conversao
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// membroTeste é um arquivo dentro de um pacote de teste
+type membroTeste struct {
+	nome     string
+	conteudo string
+}
+
+func gravarArquivo(t *testing.T, nome string, conteudo []byte) string {
+	t.Helper()
+	caminho := filepath.Join(t.TempDir(), nome)
+	if err := os.WriteFile(caminho, conteudo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return caminho
+}
+
+func compactarGzip(t *testing.T, conteudo []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(conteudo); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pacoteZip(t *testing.T, membros ...membroTeste) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for _, m := range membros {
+		w, err := z.Create(m.nome)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, m.conteudo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pacoteTar(t *testing.T, membros ...membroTeste) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, m := range membros {
+		h := &tar.Header{Name: m.nome, Mode: 0644, Size: int64(len(m.conteudo)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, m.conteudo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	dumpA = "INSERT INTO `categorias` VALUES (1,0,'a');\n"
+	dumpB = "INSERT INTO `categorias` VALUES (2,0,'b');\n"
+)
+
+func TestIdentificarArquivo(t *testing.T) {
+	um := []membroTeste{{"painel.sql", dumpA}}
+	casos := []struct {
+		nome     string
+		arquivo  string // a extensão não importa: vale o conteúdo
+		conteudo []byte
+		esperado formatoArquivo
+	}{
+		{"sql puro", "dump.zip", []byte(dumpA), arquivoSQL},
+		{"sql vazio", "dump.sql", nil, arquivoSQL},
+		{"gzip", "dump.sql", compactarGzip(t, []byte(dumpA)), arquivoGzip},
+		{"zip", "dump.sql", pacoteZip(t, um...), arquivoZip},
+		{"zip vazio", "dump.bin", pacoteZip(t), arquivoZip},
+		{"tar", "dump.sql", pacoteTar(t, um...), arquivoTar},
+		{"tar.gz", "dump.gz", compactarGzip(t, pacoteTar(t, um...)), arquivoTarGzip},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			formato, err := identificarArquivo(gravarArquivo(t, c.arquivo, c.conteudo))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formato != c.esperado {
+				t.Errorf("formato = %d, esperado %d", formato, c.esperado)
+			}
+		})
+	}
+}
+
+func TestAbrirEntrada(t *testing.T) {
+	varios := []membroTeste{
+		{"a/painel.sql", dumpA},
+		{"b/outro.SQL", dumpB},
+		{"leiame.txt", "não é dump"},
+		{"__MACOSX/a/._painel.sql", "lixo do macOS"},
+	}
+	casos := []struct {
+		nome     string
+		conteudo []byte
+		membro   string
+		dumps    []string // nomes listados por ListarDumps
+		esperado string   // conteúdo lido; vazio quando é esperado erro
+		erro     error    // erro esperado de AbrirEntrada, se houver
+	}{
+		{"sql puro", []byte(dumpA), "", []string{""}, dumpA, nil},
+		{"gzip", compactarGzip(t, []byte(dumpA)), "", []string{""}, dumpA, nil},
+		{"zip com um dump", pacoteZip(t, membroTeste{"painel.sql", dumpA}, membroTeste{"leiame.txt", "x"}), "", []string{"painel.sql"}, dumpA, nil},
+		{"zip com vários dumps", pacoteZip(t, varios...), "", []string{"a/painel.sql", "b/outro.SQL"}, "", ErrVariosDumps},
+		{"zip escolhendo o dump", pacoteZip(t, varios...), "b/outro.SQL", []string{"a/painel.sql", "b/outro.SQL"}, dumpB, nil},
+		{"tar com vários dumps", pacoteTar(t, varios...), "", []string{"a/painel.sql", "b/outro.SQL"}, "", ErrVariosDumps},
+		{"tar escolhendo o dump", pacoteTar(t, varios...), "a/painel.sql", []string{"a/painel.sql", "b/outro.SQL"}, dumpA, nil},
+		{"tar.gz com um dump", compactarGzip(t, pacoteTar(t, membroTeste{"painel.sql", dumpB})), "", []string{"painel.sql"}, dumpB, nil},
+		{"tar.gz escolhendo o dump", compactarGzip(t, pacoteTar(t, varios...)), "b/outro.SQL", []string{"a/painel.sql", "b/outro.SQL"}, dumpB, nil},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			e := Entrada{Arquivo: gravarArquivo(t, "upload", c.conteudo), Membro: c.membro}
+
+			dumps, err := ListarDumps(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var nomes []string
+			for _, d := range dumps {
+				nomes = append(nomes, d.Nome)
+			}
+			if strings.Join(nomes, ",") != strings.Join(c.dumps, ",") {
+				t.Errorf("ListarDumps = %q, esperado %q", nomes, c.dumps)
+			}
+
+			r, err := AbrirEntrada(e)
+			if c.erro != nil {
+				if !errors.Is(err, c.erro) {
+					t.Fatalf("AbrirEntrada: erro %v, esperado %v", err, c.erro)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			lido, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(lido) != c.esperado {
+				t.Errorf("conteúdo = %q, esperado %q", lido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestAbrirEntradaErros(t *testing.T) {
+	casos := []struct {
+		nome     string
+		conteudo []byte
+		membro   string
+	}{
+		{"zip sem dump", pacoteZip(t, membroTeste{"leiame.txt", "x"}), ""},
+		{"membro inexistente no zip", pacoteZip(t, membroTeste{"painel.sql", dumpA}), "outro.sql"},
+		{"membro inexistente no tar", pacoteTar(t, membroTeste{"painel.sql", dumpA}), "outro.sql"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			r, err := AbrirEntrada(Entrada{Arquivo: gravarArquivo(t, "upload", c.conteudo), Membro: c.membro})
+			if err == nil {
+				r.Close()
+				t.Fatal("AbrirEntrada deveria falhar")
+			}
+		})
+	}
+}
+
+func TestLimiteDescompactado(t *testing.T) {
+	// Um dump que compacta muito bem: poucos KB viram 1 MB
+	grande := strings.Repeat(dumpA, (1<<20)/len(dumpA))
+	casos := []struct {
+		nome     string
+		conteudo []byte
+	}{
+		{"gzip", compactarGzip(t, []byte(grande))},
+		{"zip", pacoteZip(t, membroTeste{"painel.sql", grande})},
+		{"tar.gz", compactarGzip(t, pacoteTar(t, membroTeste{"painel.sql", grande}))},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			arquivo := gravarArquivo(t, "upload", c.conteudo)
+
+			// Dentro do limite, o dump é lido inteiro
+			r, err := AbrirEntrada(Entrada{Arquivo: arquivo, LimiteDescompactado: 2 << 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lido, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || len(lido) != len(grande) {
+				t.Fatalf("lidos %d bytes (%v), esperado %d", len(lido), err, len(grande))
+			}
+
+			// Acima dele, a leitura (ou a abertura, no tar) falha
+			r, err = AbrirEntrada(Entrada{Arquivo: arquivo, LimiteDescompactado: 64 << 10})
+			if err == nil {
+				_, err = io.Copy(io.Discard, r)
+				r.Close()
+			}
+			if err == nil || !strings.Contains(err.Error(), ErrDescompactadoGrande.Error()) {
+				t.Errorf("erro = %v, esperado %v", err, ErrDescompactadoGrande)
+			}
+		})
+	}
+}
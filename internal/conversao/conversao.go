@@ -1,12 +1,14 @@
 package conversao
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -27,14 +29,14 @@ type Usuario struct {
 	Validade string  `json:"validade"`
 	Valor    float64 `json:"valor"`
 	Bloqueio int     `json:"bloqueio"`
-	Msg      string  `json:"msg"`
-	UUID     string  `json:"uuid"`
+	Msg      *string `json:"msg"`
+	UUID     *string `json:"uuid"`
 	Status   int     `json:"status"`
 	Limite   int     `json:"limite"`
 	Suspenso int     `json:"suspenso"`
 	Periodo  int     `json:"periodo"`
 	Teste    int     `json:"teste"`
-	DiaRev   string  `json:"dia_rev"`
+	DiaRev   *string `json:"dia_rev"`
 }
 
 type Revenda struct {
@@ -42,7 +44,7 @@ type Revenda struct {
 	MainID     int     `json:"mainid"`
 	Login      string  `json:"login"`
 	Senha      string  `json:"senha"`
-	Numero     string  `json:"numero"`
+	Numero     *string `json:"numero"`
 	Valor      float64 `json:"valor"`
 	Limite     int     `json:"limite"`
 	Modo       string  `json:"modo"`
@@ -51,33 +53,34 @@ type Revenda struct {
 	Categoria  int     `json:"categoria"`
 	Sub        int     `json:"sub"`
 	Expirado   int     `json:"expirado"`
-	TextoRev   string  `json:"textorev"`
-	TextoUser  string  `json:"textouser"`
-	APIKey     string  `json:"apikey"`
+	TextoRev   *string `json:"textorev"`
+	TextoUser  *string `json:"textouser"`
+	APIKey     *string `json:"apikey"`
 	Notificado int     `json:"notificado"`
-	TextoTeste string  `json:"texto_teste"`
+	TextoTeste *string `json:"texto_teste"`
 	ValorTeste float64 `json:"valor_teste"`
 	V2RayTeste int     `json:"v2ray_teste"`
 }
 
 type Database struct {
-	Categorias []Categoria `json:"categorias"`
-	Usuarios   []Usuario   `json:"usuarios"`
-	Revendas   []Revenda   `json:"revendas"`
+	Categorias []Categoria    `json:"categorias"`
+	Usuarios   []Usuario      `json:"usuarios"`
+	Revendas   []Revenda      `json:"revendas"`
+	Relatorio  RelatorioParse `json:"-"`
 }
 
 type UsuarioExport struct {
-	Login         string `json:"login"`
-	Senha         string `json:"senha"`
-	Nome          string `json:"nome"`
-	Expira        string `json:"expira"`
-	Suspenso      int    `json:"suspenso"`
-	Dono          string `json:"dono"`
-	CategoriaNome string `json:"categoria_nome"`
-	Contato       string `json:"contato"`
-	CategoriaID   int    `json:"categoriaid"`
-	Limite        int    `json:"limite"`
-	UUID          string `json:"uuid"`
+	Login         string  `json:"login"`
+	Senha         string  `json:"senha"`
+	Nome          string  `json:"nome"`
+	Expira        string  `json:"expira"`
+	Suspenso      int     `json:"suspenso"`
+	Dono          string  `json:"dono"`
+	CategoriaNome string  `json:"categoria_nome"`
+	Contato       string  `json:"contato"`
+	CategoriaID   int     `json:"categoriaid"`
+	Limite        int     `json:"limite"`
+	UUID          *string `json:"uuid"`
 }
 
 type RevendaExport struct {
@@ -100,131 +103,140 @@ type DatabaseExport struct {
 	Categorias []Categoria     `json:"categorias"`
 	Usuarios   []UsuarioExport `json:"usuarios"`
 	Revendas   []RevendaExport `json:"revendas"`
+	Relatorio  RelatorioParse  `json:"-"`
 }
 
-// Função para baixar arquivo de uma URL
-func DownloadFile(url string, filepath string) error {
-	resp, err := http.Get(url)
+// ErrArquivoGrande indica que o arquivo passa do tamanho máximo do download
+var ErrArquivoGrande = errors.New("arquivo maior que o tamanho máximo permitido")
+
+// OpcoesDownload ajusta DownloadFile. Valores zero desligam cada verificação.
+type OpcoesDownload struct {
+	TamanhoMaximo   int64         // bytes aceitos no máximo
+	TamanhoEsperado int64         // bytes informados pela origem (ex.: FileSize do Telegram)
+	Timeout         time.Duration // tempo máximo do download inteiro
+}
+
+// Função para baixar arquivo de uma URL. O conteúdo vai para um arquivo
+// temporário no mesmo diretório, renomeado para destino só quando o download
+// termina bem: em caso de erro, destino não é criado. O download é
+// interrompido se ctx for cancelado.
+func DownloadFile(ctx context.Context, url string, destino string, opcoes OpcoesDownload) error {
+	if opcoes.TamanhoMaximo > 0 && opcoes.TamanhoEsperado > opcoes.TamanhoMaximo {
+		return ErrArquivoGrande
+	}
+	if opcoes.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opcoes.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao preparar download: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return fmt.Errorf("erro ao baixar arquivo: %v", err)
 	}
 	defer resp.Body.Close()
 
-	out, err := os.Create(filepath)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("download recusado: %s", resp.Status)
+	}
+	if opcoes.TamanhoMaximo > 0 && resp.ContentLength > opcoes.TamanhoMaximo {
+		return ErrArquivoGrande
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(destino), "."+filepath.Base(destino)+".*.part")
 	if err != nil {
-		return err
+		return fmt.Errorf("erro ao criar arquivo: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // sem efeito depois do rename
+
+	corpo := io.Reader(resp.Body)
+	if opcoes.TamanhoMaximo > 0 {
+		// Um byte além do limite basta para saber que passou dele
+		corpo = io.LimitReader(resp.Body, opcoes.TamanhoMaximo+1)
+	}
+	n, err := io.Copy(tmp, corpo)
+	if errFechar := tmp.Close(); err == nil {
+		err = errFechar
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao baixar arquivo: %v", err)
+	}
+
+	if opcoes.TamanhoMaximo > 0 && n > opcoes.TamanhoMaximo {
+		return ErrArquivoGrande
+	}
+	if opcoes.TamanhoEsperado > 0 && n != opcoes.TamanhoEsperado {
+		return fmt.Errorf("download com %d bytes, esperados %d", n, opcoes.TamanhoEsperado)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if err := os.Rename(tmp.Name(), destino); err != nil {
+		return fmt.Errorf("erro ao salvar arquivo: %v", err)
+	}
+	return nil
 }
 
 // ProcessarArquivoSQL processa o arquivo SQL e retorna a estrutura de dados
 func ProcessarArquivoSQL(inputFile string) (*DatabaseExport, error) {
-	file, err := os.Open(inputFile)
+	db, err := LerArquivoSQL(context.Background(), Entrada{Arquivo: inputFile}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %v", err)
+		return nil, err
+	}
+	return TransformarDatabase(db), nil
+}
+
+// LerArquivoSQL lê um dump do painel Eclipse sem aplicar nenhuma
+// transformação, informando o andamento a acomp. A leitura para se ctx for
+// cancelado.
+func LerArquivoSQL(ctx context.Context, entrada Entrada, acomp *Acompanhamento) (*Database, error) {
+	file, err := AbrirEntrada(entrada)
+	if err != nil {
+		return nil, err
 	}
 	defer file.Close()
 
 	var db Database
-
-	scanner := bufio.NewScanner(file)
-	inInsert := false
-	currentTable := ""
-	var values string
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if strings.HasPrefix(line, "DROP TABLE") || strings.HasPrefix(line, "CREATE TABLE") {
-			continue
-		}
-
-		if strings.Contains(line, "INSERT INTO `categorias`") || strings.HasPrefix(line, "INSERT INTO categorias VALUES") {
-			inInsert = true
-			currentTable = "categorias"
-			values = ""
-			if idx := strings.Index(line, "VALUES("); idx != -1 {
-				val := line[idx+7:]
-				val = strings.TrimSuffix(val, ";")
-				val = strings.TrimSuffix(val, ")")
-				val = strings.TrimPrefix(val, "(")
-				fields := splitFields(val)
-				cat := parseCategoria(fields)
-				db.Categorias = append(db.Categorias, cat)
-				inInsert = false
-				continue
+	esquema, err := lerDump(leitorComContexto(ctx, file), colunasEclipse, &db.Relatorio, acomp, func(tabela string, reg Registro) error {
+		switch tabela {
+		case "categorias":
+			cat, err := parseCategoria(reg)
+			if err != nil {
+				return err
 			}
-			continue
-		} else if strings.Contains(line, "INSERT INTO `revenda`") || strings.HasPrefix(line, "INSERT INTO revenda VALUES") {
-			inInsert = true
-			currentTable = "revenda"
-			values = ""
-			if idx := strings.Index(line, "VALUES("); idx != -1 {
-				val := line[idx+7:]
-				val = strings.TrimSuffix(val, ";")
-				val = strings.TrimSuffix(val, ")")
-				val = strings.TrimPrefix(val, "(")
-				fields := splitFields(val)
-				rev := parseRevenda(fields)
-				db.Revendas = append(db.Revendas, rev)
-				inInsert = false
-				continue
+			db.Categorias = append(db.Categorias, cat)
+		case "revenda":
+			rev, err := parseRevenda(reg)
+			if err != nil {
+				return err
 			}
-			continue
-		} else if strings.Contains(line, "INSERT INTO `usuarios`") || strings.HasPrefix(line, "INSERT INTO usuarios VALUES") {
-			inInsert = true
-			currentTable = "usuarios"
-			values = ""
-			if idx := strings.Index(line, "VALUES("); idx != -1 {
-				val := line[idx+7:]
-				val = strings.TrimSuffix(val, ";")
-				val = strings.TrimSuffix(val, ")")
-				val = strings.TrimPrefix(val, "(")
-				fields := splitFields(val)
-				user := parseUsuario(fields)
-				db.Usuarios = append(db.Usuarios, user)
-				inInsert = false
-				continue
-			}
-			continue
-		}
-
-		if inInsert && strings.HasPrefix(line, "(") {
-			values += line
-			if strings.HasSuffix(strings.TrimSpace(line), ";") {
-				values = strings.TrimSuffix(strings.TrimSpace(values), ";")
-				rows := strings.Split(values, "),(")
-				for _, row := range rows {
-					row = strings.Trim(row, "()")
-					fields := splitFields(row)
-					switch currentTable {
-					case "categorias":
-						cat := parseCategoria(fields)
-						db.Categorias = append(db.Categorias, cat)
-					case "revenda":
-						rev := parseRevenda(fields)
-						db.Revendas = append(db.Revendas, rev)
-					case "usuarios":
-						user := parseUsuario(fields)
-						db.Usuarios = append(db.Usuarios, user)
-					}
-				}
-				inInsert = false
-				values = ""
+			db.Revendas = append(db.Revendas, rev)
+		case "usuarios":
+			user, err := parseUsuario(reg)
+			if err != nil {
+				return err
 			}
-		} else if inInsert {
-			values += line
+			db.Usuarios = append(db.Usuarios, user)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Monta os dados de exportação com os campos extras
+	db.Relatorio.ColunasDesconhecidas = esquema.ColunasDesconhecidas(colunasEclipse)
+	return &db, nil
+}
+
+// TransformarDatabase monta os dados de exportação com os campos extras
+func TransformarDatabase(db *Database) *DatabaseExport {
 	var dbExport DatabaseExport
+	dbExport.Relatorio = db.Relatorio
 	dbExport.Categorias = db.Categorias
 	for _, user := range db.Usuarios {
-		contato := strings.TrimSpace(user.Msg)
+		contato := Texto(user.Msg)
 		if contato == "" && len(user.Login) > 0 {
 			contato = gerarContatoAleatorio()
 		}
@@ -249,8 +261,8 @@ func ProcessarArquivoSQL(inputFile string) (*DatabaseExport, error) {
 			Nome:          user.Nome,
 			Expira:        expira,
 			Suspenso:      user.Suspenso,
-			Dono:          getDonoUsuario(user, db),
-			CategoriaNome: getNomeCategoriaPorSubID(user.SubID, db),
+			Dono:          getDonoUsuario(user, *db),
+			CategoriaNome: getNomeCategoriaPorSubID(user.SubID, *db),
 			Contato:       contato,
 			CategoriaID:   user.SubID,
 			Limite:        user.Limite,
@@ -262,7 +274,7 @@ func ProcessarArquivoSQL(inputFile string) (*DatabaseExport, error) {
 		if t, err := time.Parse("2006-01-02", rev.Data); err == nil {
 			dataFormatada = t.Format("2006-01-02T15:04:05")
 		}
-		contato := strings.TrimSpace(rev.Numero)
+		contato := Texto(rev.Numero)
 		if contato == "" {
 			contato = gerarContatoAleatorio()
 		}
@@ -286,102 +298,89 @@ func ProcessarArquivoSQL(inputFile string) (*DatabaseExport, error) {
 			Expira:        dataFormatada,
 			CategoriaID:   rev.Categoria,
 			Sub:           rev.Sub,
-			Dono:          getDonoRevenda(rev, db),
-			CategoriaNome: getNomeCategoriaPorSubID(rev.Categoria, db),
+			Dono:          getDonoRevenda(rev, *db),
+			CategoriaNome: getNomeCategoriaPorSubID(rev.Categoria, *db),
 			Nome:          rev.Login,
 			Email:         email,
 		})
 	}
 
-	return &dbExport, nil
+	return &dbExport
 }
 
-func splitFields(row string) []string {
-	var fields []string
-	var field string
-	inQuote := false
-	quoteChar := byte(0)
-
-	for i := 0; i < len(row); i++ {
-		char := row[i]
-		if (char == '\'' || char == '"') && !inQuote {
-			inQuote = true
-			quoteChar = char
-			continue
-		}
-		if inQuote && char == quoteChar {
-			inQuote = false
-			continue
-		}
-		if char == ',' && !inQuote {
-			fields = append(fields, field)
-			field = ""
-		} else {
-			field += string(char)
-		}
-	}
-	if field != "" {
-		fields = append(fields, field)
-	}
-	return fields
+// colunasEclipse é a ordem das colunas no painel Eclipse, usada quando o
+// dump não traz a lista de colunas nem o CREATE TABLE
+var colunasEclipse = map[string][]string{
+	"categorias": {"id", "subid", "nome"},
+	"usuarios": {"id", "mainid", "subid", "login", "senha", "nome", "validade", "valor", "bloqueio",
+		"msg", "uuid", "status", "limite", "suspenso", "periodo", "teste", "dia_rev"},
+	"revenda": {"id", "mainid", "login", "senha", "numero", "valor", "limite", "modo", "data",
+		"limite_use", "categoria", "sub", "expirado", "textorev", "textouser", "apikey",
+		"notificado", "texto_teste", "valor_teste", "v2ray_teste"},
 }
 
-func parseCategoria(fields []string) Categoria {
+func parseCategoria(reg Registro) (Categoria, error) {
 	var cat Categoria
-	fmt.Sscanf(fields[0], "%d", &cat.ID)
-	fmt.Sscanf(fields[1], "%d", &cat.SubID)
-	cat.Nome = strings.TrimSpace(fields[2])
-	return cat
+	if err := reg.exigir("id"); err != nil {
+		return cat, err
+	}
+	cat.ID = reg.Campo("id").Int()
+	cat.SubID = reg.Campo("subid").Int()
+	cat.Nome = reg.Campo("nome").String()
+	return cat, nil
 }
 
-func parseUsuario(fields []string) Usuario {
-	for len(fields) < 17 {
-		fields = append(fields, "")
-	}
+func parseUsuario(reg Registro) (Usuario, error) {
 	var user Usuario
-	fmt.Sscanf(fields[0], "%d", &user.ID)
-	fmt.Sscanf(fields[1], "%d", &user.MainID)
-	fmt.Sscanf(fields[2], "%d", &user.SubID)
-	user.Login = strings.TrimSpace(fields[3])
-	user.Senha = strings.TrimSpace(fields[4])
-	user.Nome = strings.TrimSpace(fields[5])
-	user.Validade = strings.TrimSpace(fields[6])
-	fmt.Sscanf(fields[7], "%f", &user.Valor)
-	fmt.Sscanf(fields[8], "%d", &user.Bloqueio)
-	user.Msg = strings.TrimSpace(fields[9])
-	user.UUID = strings.TrimSpace(fields[10])
-	fmt.Sscanf(fields[11], "%d", &user.Status)
-	fmt.Sscanf(fields[12], "%d", &user.Limite)
-	fmt.Sscanf(fields[13], "%d", &user.Suspenso)
-	fmt.Sscanf(fields[14], "%d", &user.Periodo)
-	fmt.Sscanf(fields[15], "%d", &user.Teste)
-	user.DiaRev = strings.TrimSpace(fields[16])
-	return user
+	if err := reg.exigir("login"); err != nil {
+		return user, err
+	}
+	user.ID = reg.Campo("id").Int()
+	user.MainID = reg.Campo("mainid").Int()
+	user.SubID = reg.Campo("subid").Int()
+	user.Login = reg.Campo("login").String()
+	user.Senha = reg.Campo("senha").String()
+	user.Nome = reg.Campo("nome").String()
+	user.Validade = reg.Campo("validade").String()
+	user.Valor = reg.Campo("valor").Float()
+	user.Bloqueio = reg.Campo("bloqueio").Int()
+	user.Msg = reg.Campo("msg").Ptr()
+	user.UUID = reg.Campo("uuid").Ptr()
+	user.Status = reg.Campo("status").Int()
+	user.Limite = reg.Campo("limite").Int()
+	user.Suspenso = reg.Campo("suspenso").Int()
+	user.Periodo = reg.Campo("periodo").Int()
+	user.Teste = reg.Campo("teste").Int()
+	user.DiaRev = reg.Campo("dia_rev").Ptr()
+	return user, nil
 }
 
-func parseRevenda(fields []string) Revenda {
+func parseRevenda(reg Registro) (Revenda, error) {
 	var rev Revenda
-	fmt.Sscanf(fields[0], "%d", &rev.ID)
-	fmt.Sscanf(fields[1], "%d", &rev.MainID)
-	rev.Login = strings.TrimSpace(fields[2])
-	rev.Senha = strings.TrimSpace(fields[3])
-	rev.Numero = strings.TrimSpace(fields[4])
-	fmt.Sscanf(fields[5], "%f", &rev.Valor)
-	fmt.Sscanf(fields[6], "%d", &rev.Limite)
-	rev.Modo = strings.TrimSpace(fields[7])
-	rev.Data = strings.TrimSpace(fields[8])
-	fmt.Sscanf(fields[9], "%d", &rev.LimiteUse)
-	fmt.Sscanf(fields[10], "%d", &rev.Categoria)
-	fmt.Sscanf(fields[11], "%d", &rev.Sub)
-	fmt.Sscanf(fields[12], "%d", &rev.Expirado)
-	rev.TextoRev = strings.TrimSpace(fields[13])
-	rev.TextoUser = strings.TrimSpace(fields[14])
-	rev.APIKey = strings.TrimSpace(fields[15])
-	fmt.Sscanf(fields[16], "%d", &rev.Notificado)
-	rev.TextoTeste = strings.TrimSpace(fields[17])
-	fmt.Sscanf(fields[18], "%f", &rev.ValorTeste)
-	fmt.Sscanf(fields[19], "%d", &rev.V2RayTeste)
-	return rev
+	if err := reg.exigir("id", "login"); err != nil {
+		return rev, err
+	}
+	rev.ID = reg.Campo("id").Int()
+	rev.MainID = reg.Campo("mainid").Int()
+	rev.Login = reg.Campo("login").String()
+	rev.Senha = reg.Campo("senha").String()
+	rev.Numero = reg.Campo("numero").Ptr()
+	rev.Valor = reg.Campo("valor").Float()
+	rev.Limite = reg.Campo("limite").Int()
+	rev.Modo = reg.Campo("modo").String()
+	rev.Data = reg.Campo("data").String()
+	rev.LimiteUse = reg.Campo("limite_use").Int()
+	rev.Categoria = reg.Campo("categoria").Int()
+	rev.Sub = reg.Campo("sub").Int()
+	rev.Expirado = reg.Campo("expirado").Int()
+	rev.TextoRev = reg.Campo("textorev").Ptr()
+	rev.TextoUser = reg.Campo("textouser").Ptr()
+	rev.APIKey = reg.Campo("apikey").Ptr()
+	rev.Notificado = reg.Campo("notificado").Int()
+	rev.TextoTeste = reg.Campo("texto_teste").Ptr()
+	rev.ValorTeste = reg.Campo("valor_teste").Float()
+	rev.V2RayTeste = reg.Campo("v2ray_teste").Int()
+	return rev, nil
 }
 
 func getDonoRevenda(rev Revenda, db Database) string {
@@ -0,0 +1,132 @@
+package conversao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadFile(t *testing.T) {
+	conteudo := strings.Repeat("x", 1000)
+	casos := []struct {
+		nome   string
+		opcoes OpcoesDownload
+		rota   http.HandlerFunc
+		erro   error  // erro esperado, quando conhecido
+		falha  string // trecho esperado na mensagem de erro
+	}{
+		{
+			nome: "sucesso",
+			rota: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(conteudo)) },
+		},
+		{
+			nome:  "status de erro",
+			rota:  func(w http.ResponseWriter, r *http.Request) { http.Error(w, "sumiu", http.StatusNotFound) },
+			falha: "404",
+		},
+		{
+			nome:   "Content-Length acima do máximo",
+			opcoes: OpcoesDownload{TamanhoMaximo: 999},
+			rota:   func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(conteudo)) },
+			erro:   ErrArquivoGrande,
+		},
+		{
+			nome:   "corpo acima do máximo sem Content-Length",
+			opcoes: OpcoesDownload{TamanhoMaximo: 999},
+			rota: func(w http.ResponseWriter, r *http.Request) {
+				// O Flush faz a resposta sair em chunks, sem tamanho declarado
+				w.Write([]byte(conteudo[:500]))
+				w.(http.Flusher).Flush()
+				w.Write([]byte(conteudo[500:]))
+			},
+			erro: ErrArquivoGrande,
+		},
+		{
+			nome:   "tamanho esperado acima do máximo",
+			opcoes: OpcoesDownload{TamanhoMaximo: 999, TamanhoEsperado: 1000},
+			rota:   func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(conteudo)) },
+			erro:   ErrArquivoGrande,
+		},
+		{
+			nome:   "menos bytes que o esperado",
+			opcoes: OpcoesDownload{TamanhoEsperado: 1001},
+			rota:   func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(conteudo)) },
+			falha:  "esperados 1001",
+		},
+		{
+			nome:   "tempo esgotado",
+			opcoes: OpcoesDownload{Timeout: 50 * time.Millisecond},
+			rota: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(conteudo[:10]))
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			},
+			falha: "deadline",
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			servidor := httptest.NewServer(c.rota)
+			defer servidor.Close()
+
+			dir := t.TempDir()
+			destino := filepath.Join(dir, "dump.sql")
+			if err := os.WriteFile(destino, []byte("anterior"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := DownloadFile(context.Background(), servidor.URL, destino, c.opcoes)
+			sucesso := c.erro == nil && c.falha == ""
+			switch {
+			case sucesso && err != nil:
+				t.Fatalf("erro inesperado: %v", err)
+			case c.erro != nil && !errors.Is(err, c.erro):
+				t.Fatalf("erro = %v, esperado %v", err, c.erro)
+			case c.falha != "" && (err == nil || !strings.Contains(err.Error(), c.falha)):
+				t.Fatalf("erro = %v, esperado com %q", err, c.falha)
+			}
+
+			esperado := "anterior"
+			if sucesso {
+				esperado = conteudo
+			}
+			if lido, _ := os.ReadFile(destino); string(lido) != esperado {
+				t.Errorf("destino com %d bytes, esperado %d", len(lido), len(esperado))
+			}
+			// Nenhum arquivo temporário fica para trás
+			if entradas, _ := os.ReadDir(dir); len(entradas) != 1 {
+				var nomes []string
+				for _, e := range entradas {
+					nomes = append(nomes, e.Name())
+				}
+				t.Errorf("arquivos no diretório: %v", nomes)
+			}
+		})
+	}
+}
+
+func TestDownloadFileCancelado(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("não deveria baixar")
+	}))
+	defer servidor.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	destino := filepath.Join(t.TempDir(), "dump.sql")
+	if err := DownloadFile(ctx, servidor.URL, destino, OpcoesDownload{}); err == nil {
+		t.Fatal("download com ctx cancelado deveria falhar")
+	}
+	if _, err := os.Stat(destino); !os.IsNotExist(err) {
+		t.Errorf("destino criado apesar da falha: %v", err)
+	}
+}
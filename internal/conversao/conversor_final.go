@@ -1,10 +1,8 @@
 package conversao
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
-	"strings"
 	"time"
 )
 
@@ -14,52 +12,53 @@ type DatabaseFinal struct {
 	SSHAccounts []SSHAccountFinal
 	Atribuidos  []AtribuidoFinal
 	Categorias  []CategoriaFinal
+	Relatorio   RelatorioParse
 }
 
 type AccountFinal struct {
-	ID            int    `json:"id"`
-	Nome          string `json:"nome"`
-	Contato       string `json:"contato"`
-	Email         string `json:"email"`
-	Login         string `json:"login"`
-	Token         string `json:"token"`
-	MB            string `json:"mb"`
-	Senha         string `json:"senha"`
-	ByID          string `json:"byid"`
-	MainID        string `json:"mainid"`
-	AccessToken   string `json:"accesstoken"`
-	ValorUsuario  string `json:"valorusuario"`
-	ValorRevenda  string `json:"valorrevenda"`
-	IDTelegram    string `json:"idtelegram"`
-	Tempo         string `json:"tempo"`
-	TokenVenda    string `json:"tokenvenda"`
-	TokenPagHiper string `json:"tokenpaghiper"`
-	FormaDePag    string `json:"formadepag"`
-	WhatsApp      string `json:"whatsapp"`
-	Nivel         int    `json:"nivel"`
+	ID            int     `json:"id"`
+	Nome          string  `json:"nome"`
+	Contato       string  `json:"contato"`
+	Email         string  `json:"email"`
+	Login         string  `json:"login"`
+	Token         *string `json:"token"`
+	MB            string  `json:"mb"`
+	Senha         string  `json:"senha"`
+	ByID          string  `json:"byid"`
+	MainID        string  `json:"mainid"`
+	AccessToken   *string `json:"accesstoken"`
+	ValorUsuario  string  `json:"valorusuario"`
+	ValorRevenda  string  `json:"valorrevenda"`
+	IDTelegram    *string `json:"idtelegram"`
+	Tempo         string  `json:"tempo"`
+	TokenVenda    *string `json:"tokenvenda"`
+	TokenPagHiper *string `json:"tokenpaghiper"`
+	FormaDePag    *string `json:"formadepag"`
+	WhatsApp      *string `json:"whatsapp"`
+	Nivel         int     `json:"nivel"`
 }
 
 type SSHAccountFinal struct {
-	ID          int    `json:"id"`
-	ByID        int    `json:"byid"`
-	CategoriaID int    `json:"categoriaid"`
-	Limite      int    `json:"limite"`
-	ByCredit    int    `json:"bycredit"`
-	Login       string `json:"login"`
-	Nome        string `json:"nome"`
-	Senha       string `json:"senha"`
-	MainID      string `json:"mainid"`
-	Expira      string `json:"expira"`
-	LastView    string `json:"lastview"`
-	Status      string `json:"status"`
-	ValorMensal string `json:"valormensal"`
-	Notificado  string `json:"notificado"`
-	WhatsApp    string `json:"whatsapp"`
-	UUID        string `json:"uuid"`
-	DeviceID    string `json:"deviceid"`
-	DeviceAtivo string `json:"deviceativo"`
-	Contato     string `json:"contato"`
-	Tipo        string `json:"tipo"`
+	ID          int     `json:"id"`
+	ByID        int     `json:"byid"`
+	CategoriaID int     `json:"categoriaid"`
+	Limite      int     `json:"limite"`
+	ByCredit    int     `json:"bycredit"`
+	Login       string  `json:"login"`
+	Nome        string  `json:"nome"`
+	Senha       string  `json:"senha"`
+	MainID      string  `json:"mainid"`
+	Expira      string  `json:"expira"`
+	LastView    *string `json:"lastview"`
+	Status      string  `json:"status"`
+	ValorMensal string  `json:"valormensal"`
+	Notificado  string  `json:"notificado"`
+	WhatsApp    *string `json:"whatsapp"`
+	UUID        *string `json:"uuid"`
+	DeviceID    *string `json:"deviceid"`
+	DeviceAtivo *string `json:"deviceativo"`
+	Contato     string  `json:"contato"`
+	Tipo        string  `json:"tipo"`
 }
 
 type AtribuidoFinal struct {
@@ -87,9 +86,20 @@ type CategoriaFinal struct {
 
 // ProcessarArquivoSQLFinal processa um arquivo SQL que já está no formato final
 func ProcessarArquivoSQLFinal(inputFile string) (*DatabaseFinal, error) {
-	file, err := os.Open(inputFile)
+	db, err := LerArquivoSQLFinal(context.Background(), Entrada{Arquivo: inputFile}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %v", err)
+		return nil, err
+	}
+	AjustarMainIDs(db)
+	return db, nil
+}
+
+// LerArquivoSQLFinal lê um dump no formato final sem ajustar os mainids,
+// informando o andamento a acomp. A leitura para se ctx for cancelado.
+func LerArquivoSQLFinal(ctx context.Context, entrada Entrada, acomp *Acompanhamento) (*DatabaseFinal, error) {
+	file, err := AbrirEntrada(entrada)
+	if err != nil {
+		return nil, err
 	}
 	defer file.Close()
 
@@ -100,79 +110,45 @@ func ProcessarArquivoSQLFinal(inputFile string) (*DatabaseFinal, error) {
 		Categorias:  make([]CategoriaFinal, 0),
 	}
 
-	scanner := bufio.NewScanner(file)
-	inInsert := false
-	currentTable := ""
-	var values string
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if strings.HasPrefix(line, "DROP TABLE") || strings.HasPrefix(line, "CREATE TABLE") {
-			continue
-		}
-
-		// Accounts
-		if strings.HasPrefix(line, "INSERT INTO `accounts`") || strings.HasPrefix(line, "INSERT INTO accounts") {
-			inInsert = true
-			currentTable = "accounts"
-			values = ""
-			if idx := strings.Index(line, "VALUES"); idx != -1 {
-				values = line[idx+6:] // Pega só o trecho após VALUES
-				if strings.HasSuffix(values, ";") {
-					values = strings.TrimSuffix(values, ";")
-					processValues(values, currentTable, db)
-					inInsert = false
-				}
-			}
-		} else if strings.HasPrefix(line, "INSERT INTO `ssh_accounts`") || strings.HasPrefix(line, "INSERT INTO ssh_accounts") {
-			inInsert = true
-			currentTable = "ssh_accounts"
-			values = ""
-			if idx := strings.Index(line, "VALUES"); idx != -1 {
-				values = line[idx+6:]
-				if strings.HasSuffix(values, ";") {
-					values = strings.TrimSuffix(values, ";")
-					processValues(values, currentTable, db)
-					inInsert = false
-				}
+	esquema, err := lerDump(leitorComContexto(ctx, file), colunasFinal, &db.Relatorio, acomp, func(tabela string, reg Registro) error {
+		switch tabela {
+		case "accounts":
+			acc, err := parseAccountFinal(reg)
+			if err != nil {
+				return err
 			}
-		} else if strings.HasPrefix(line, "INSERT INTO `atribuidos`") || strings.HasPrefix(line, "INSERT INTO atribuidos") {
-			inInsert = true
-			currentTable = "atribuidos"
-			values = ""
-			if idx := strings.Index(line, "VALUES"); idx != -1 {
-				values = line[idx+6:]
-				if strings.HasSuffix(values, ";") {
-					values = strings.TrimSuffix(values, ";")
-					processValues(values, currentTable, db)
-					inInsert = false
-				}
+			db.Accounts = append(db.Accounts, acc)
+		case "ssh_accounts":
+			ssh, err := parseSSHAccountFinal(reg)
+			if err != nil {
+				return err
 			}
-		} else if strings.HasPrefix(line, "INSERT INTO `categorias`") || strings.HasPrefix(line, "INSERT INTO categorias") {
-			inInsert = true
-			currentTable = "categorias"
-			values = ""
-			if idx := strings.Index(line, "VALUES"); idx != -1 {
-				values = line[idx+6:]
-				if strings.HasSuffix(values, ";") {
-					values = strings.TrimSuffix(values, ";")
-					processValues(values, currentTable, db)
-					inInsert = false
-				}
+			db.SSHAccounts = append(db.SSHAccounts, ssh)
+		case "atribuidos":
+			atr, err := parseAtribuidoFinal(reg)
+			if err != nil {
+				return err
 			}
-		}
-
-		if inInsert {
-			values += line
-			if strings.HasSuffix(line, ";") {
-				values = strings.TrimSuffix(values, ";")
-				processValues(values, currentTable, db)
-				inInsert = false
+			db.Atribuidos = append(db.Atribuidos, atr)
+		case "categorias":
+			cat, err := parseCategoriaFinal(reg)
+			if err != nil {
+				return err
 			}
+			db.Categorias = append(db.Categorias, cat)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	db.Relatorio.ColunasDesconhecidas = esquema.ColunasDesconhecidas(colunasFinal)
+	return db, nil
+}
+
+// AjustarMainIDs gera os mainids das revendas e propaga para os usuários SSH
+func AjustarMainIDs(db *DatabaseFinal) {
 	// --- AJUSTE MAINID DOS ACCOUNTS (exceto admin) ---
 	mainidMap := make(map[int]string)
 	for i, acc := range db.Accounts {
@@ -197,185 +173,61 @@ func ProcessarArquivoSQLFinal(inputFile string) (*DatabaseFinal, error) {
 		}
 		db.SSHAccounts[i].MainID = mainid
 	}
-
-	return db, nil
-}
-
-func processValues(values string, table string, db *DatabaseFinal) {
-	values = strings.TrimPrefix(values, "VALUES")
-	values = strings.TrimSpace(values)
-
-	// Garante que começa exatamente no primeiro '('
-	idx := strings.Index(values, "(")
-	if idx != -1 {
-		values = values[idx:]
-	}
-
-	// Remove possível vírgula final
-	values = strings.TrimSuffix(values, ";")
-
-	// Divide os registros corretamente
-	rows := splitInsertRows(values)
-
-	for _, row := range rows {
-		row = strings.Trim(row, "() ")
-		fields := splitFieldsFinal(row)
-		if len(fields) == 0 {
-			continue
-		}
-		if isHeaderRow(fields) {
-			continue
-		}
-
-		switch table {
-		case "accounts":
-			acc := parseAccountFinal(fields)
-			db.Accounts = append(db.Accounts, acc)
-		case "ssh_accounts":
-			ssh := parseSSHAccountFinal(fields)
-			db.SSHAccounts = append(db.SSHAccounts, ssh)
-		case "atribuidos":
-			atr := parseAtribuidoFinal(fields)
-			db.Atribuidos = append(db.Atribuidos, atr)
-		case "categorias":
-			cat := parseCategoriaFinal(fields)
-			db.Categorias = append(db.Categorias, cat)
-		}
-	}
-}
-
-// Função robusta para dividir os registros do insert
-func splitInsertRows(values string) []string {
-	var rows []string
-	var buf strings.Builder
-	open := 0
-	for i := 0; i < len(values); i++ {
-		c := values[i]
-		if c == '(' {
-			if open == 0 && buf.Len() > 0 {
-				buf.Reset()
-			}
-			open++
-		}
-		if open > 0 {
-			buf.WriteByte(c)
-		}
-		if c == ')' {
-			open--
-			if open == 0 {
-				rows = append(rows, buf.String())
-				buf.Reset()
-			}
-		}
-	}
-	return rows
 }
 
-// Função auxiliar para detectar se a linha é header de nomes de colunas
-func isHeaderRow(fields []string) bool {
-	colunasPossiveis := map[string]bool{
-		"id": true, "nome": true, "contato": true, "email": true, "login": true, "token": true, "mb": true, "senha": true,
-		"byid": true, "mainid": true, "accesstoken": true, "valorusuario": true, "valorrevenda": true,
-		"idtelegram": true, "tempo": true, "tokenvenda": true, "acesstokenpaghiper": true, "formadepag": true,
-		"tokenpaghiper": true, "whatsapp": true, "categoriaid": true, "userid": true, "limite": true,
-		"limitetest": true, "tipo": true, "expira": true, "subrev": true, "suspenso": true, "valormensal": true,
-		"notificado": true, "susid": true, "subid": true,
-	}
-	count := 0
-	for _, f := range fields {
-		nome := strings.ToLower(strings.Trim(f, " `'\""))
-		// Se o campo for só letras e for uma coluna possível, conta
-		if colunasPossiveis[nome] {
-			count++
-		}
-		// Se o campo for só letras/crases/aspas, provavelmente é header
-		if len(nome) > 0 && isOnlyLetters(nome) && colunasPossiveis[nome] {
-			count++
-		}
-	}
-	// Se a maioria dos campos são nomes de colunas, é header
-	return count > len(fields)/2 && len(fields) > 0
+// colunasFinal é a ordem das colunas no painel Atlas, usada quando o dump
+// não traz a lista de colunas nem o CREATE TABLE. Em accounts, as colunas
+// que a tabela do Atlas ganhou depois (email, recuperar_senha e nivel) vêm
+// no fim, para que dumps antigos, sem elas, continuem casando pela posição.
+var colunasFinal = map[string][]string{
+	"accounts": {"id", "nome", "contato", "login", "token", "mb", "senha", "byid", "mainid",
+		"accesstoken", "valorusuario", "valorrevenda", "idtelegram", "tempo", "tokenvenda",
+		"tokenpaghiper", "formadepag", "whatsapp", "email", "recuperar_senha", "nivel"},
+	"ssh_accounts": {"id", "byid", "categoriaid", "limite", "bycredit", "login", "senha", "mainid",
+		"expira", "lastview", "status", "valormensal", "notificado", "whatsapp", "uuid",
+		"deviceid", "deviceativo"},
+	"atribuidos": {"id", "valor", "categoriaid", "userid", "byid", "limite", "limitetest", "tipo",
+		"expira", "subrev", "suspenso", "valormensal", "notificado"},
+	"categorias": {"id", "subid", "nome"},
 }
 
-// Função auxiliar para verificar se a string contém apenas letras
-func isOnlyLetters(s string) bool {
-	for _, r := range s {
-		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
-			return false
-		}
-	}
-	return true
-}
-
-// Função auxiliar para dividir os campos de uma linha SQL considerando aspas
-func splitFieldsFinal(row string) []string {
-	var fields []string
-	var field string
-	inQuote := false
-	quoteChar := byte(0)
-
-	for i := 0; i < len(row); i++ {
-		char := row[i]
-		if (char == '\'' || char == '"') && !inQuote {
-			inQuote = true
-			quoteChar = char
-			continue
-		}
-		if inQuote && char == quoteChar {
-			inQuote = false
-			continue
-		}
-		if char == ',' && !inQuote {
-			fields = append(fields, field)
-			field = ""
-		} else {
-			field += string(char)
-		}
-	}
-	if field != "" {
-		fields = append(fields, field)
-	}
-	return fields
-}
-
-func parseAccountFinal(fields []string) AccountFinal {
+func parseAccountFinal(reg Registro) (AccountFinal, error) {
 	var acc AccountFinal
-	if len(fields) < 18 {
-		fmt.Printf("[parseAccountFinal] ERRO: esperado pelo menos 18 campos, recebido %d: %#v\n", len(fields), fields)
-		return acc // retorna struct zerada para evitar panic
-	}
-	fmt.Sscanf(fields[0], "%d", &acc.ID)
-	acc.Nome = strings.TrimSpace(fields[1])
-	acc.Contato = strings.TrimSpace(fields[2])
-	acc.Login = strings.TrimSpace(fields[3])
-	acc.Token = strings.TrimSpace(fields[4])
-	acc.MB = strings.TrimSpace(fields[5])
-	acc.Senha = strings.TrimSpace(fields[6])
-	acc.ByID = strings.TrimSpace(fields[7])
+	if err := reg.exigir("id", "login"); err != nil {
+		return acc, err
+	}
+	acc.ID = reg.Campo("id").Int()
+	acc.Nome = reg.Campo("nome").String()
+	acc.Contato = reg.Campo("contato").String()
+	acc.Login = reg.Campo("login").String()
+	acc.Token = reg.Campo("token").Ptr()
+	acc.MB = reg.Campo("mb").String()
+	acc.Senha = reg.Campo("senha").String()
+	acc.ByID = reg.Campo("byid").String()
 	if acc.ByID == "" {
 		acc.ByID = "1"
 	}
-	acc.MainID = strings.TrimSpace(fields[8])
+	acc.MainID = reg.Campo("mainid").String()
 	if acc.MainID == "" {
 		acc.MainID = "1"
 	}
-	acc.AccessToken = strings.TrimSpace(fields[9])
-	acc.ValorUsuario = strings.TrimSpace(fields[10])
-	acc.ValorRevenda = strings.TrimSpace(fields[11])
-	acc.IDTelegram = strings.TrimSpace(fields[12])
-	acc.Tempo = strings.TrimSpace(fields[13])
-	acc.TokenVenda = strings.TrimSpace(fields[14])
-	acc.TokenPagHiper = strings.TrimSpace(fields[15])
-	acc.FormaDePag = strings.TrimSpace(fields[16])
-	acc.WhatsApp = strings.TrimSpace(fields[17])
-
-	// Nome: se vazio ou 'NULL', usar login
-	if acc.Nome == "" || strings.ToUpper(acc.Nome) == "NULL" {
+	acc.AccessToken = reg.Campo("accesstoken").Ptr()
+	acc.ValorUsuario = reg.Campo("valorusuario").String()
+	acc.ValorRevenda = reg.Campo("valorrevenda").String()
+	acc.IDTelegram = reg.Campo("idtelegram").Ptr()
+	acc.Tempo = reg.Campo("tempo").String()
+	acc.TokenVenda = reg.Campo("tokenvenda").Ptr()
+	acc.TokenPagHiper = reg.Campo("tokenpaghiper").Ptr()
+	acc.FormaDePag = reg.Campo("formadepag").Ptr()
+	acc.WhatsApp = reg.Campo("whatsapp").Ptr()
+
+	// Nome: se NULL ou vazio, usar login
+	if acc.Nome == "" {
 		acc.Nome = acc.Login
 	}
-	// Contato: se vazio ou 'NULL', usar número exemplo
-	if acc.Contato == "" || strings.ToUpper(acc.Contato) == "NULL" {
-		acc.Contato = "62999999999"
+	// Contato: se NULL ou vazio, usar número exemplo
+	if acc.Contato == "" {
+		acc.Contato = contatoExemplo
 	}
 	// Email: <login>@gmail.com
 	acc.Email = acc.Login + "@gmail.com"
@@ -391,84 +243,53 @@ func parseAccountFinal(fields []string) AccountFinal {
 	} else {
 		acc.Nivel = 2
 	}
-	return acc
+	return acc, nil
 }
 
+const (
+	// contatoExemplo substitui contatos ausentes no formato final
+	contatoExemplo = "62999999999"
+	// dataInvalida substitui datas fora do intervalo aceito pelo painel
+	dataInvalida = "2000-01-01 00:00:00"
+)
+
 func validarDataMySQL(data string) string {
 	t, err := time.Parse("2006-01-02 15:04:05", data)
 	if err != nil || t.Year() < 2000 || t.Year() > 2100 {
-		return "2000-01-01 00:00:00"
+		return dataInvalida
 	}
 	return data
 }
 
-func parseSSHAccountFinal(fields []string) SSHAccountFinal {
-	// Garante que temos campos suficientes
-	for len(fields) < 17 {
-		fields = append(fields, "")
-	}
-
+func parseSSHAccountFinal(reg Registro) (SSHAccountFinal, error) {
 	var ssh SSHAccountFinal
-
-	// Campos obrigatórios com valores padrão se vazios
-	if len(fields) > 0 {
-		fmt.Sscanf(fields[0], "%d", &ssh.ID)
-	}
-	if len(fields) > 1 {
-		fmt.Sscanf(fields[1], "%d", &ssh.ByID)
-	}
-	if len(fields) > 2 {
-		fmt.Sscanf(fields[2], "%d", &ssh.CategoriaID)
-	}
-	if len(fields) > 3 {
-		fmt.Sscanf(fields[3], "%d", &ssh.Limite)
-	}
-	if len(fields) > 4 {
-		fmt.Sscanf(fields[4], "%d", &ssh.ByCredit)
+	if err := reg.exigir("login"); err != nil {
+		return ssh, err
 	}
 
-	// Campos string podem ser vazios
-	if len(fields) > 5 {
-		ssh.Login = strings.TrimSpace(fields[5])
-	}
+	ssh.ID = reg.Campo("id").Int()
+	ssh.ByID = reg.Campo("byid").Int()
+	ssh.CategoriaID = reg.Campo("categoriaid").Int()
+	ssh.Limite = reg.Campo("limite").Int()
+	ssh.ByCredit = reg.Campo("bycredit").Int()
+
+	ssh.Login = reg.Campo("login").String()
 	// Nome: sempre igual ao login
 	ssh.Nome = ssh.Login
-	if len(fields) > 6 {
-		ssh.Senha = strings.TrimSpace(fields[6])
-	}
-	if len(fields) > 7 {
-		ssh.MainID = strings.TrimSpace(fields[7])
-	}
-	if len(fields) > 8 {
-		ssh.Expira = validarDataMySQL(strings.TrimSpace(fields[8]))
-	}
-	if len(fields) > 9 {
-		ssh.LastView = strings.TrimSpace(fields[9])
-	}
-	if len(fields) > 10 {
-		ssh.Status = strings.TrimSpace(fields[10])
-	}
-	if len(fields) > 11 {
-		ssh.ValorMensal = strings.TrimSpace(fields[11])
-	}
-	if len(fields) > 12 {
-		ssh.Notificado = strings.TrimSpace(fields[12])
-	}
-	if len(fields) > 13 {
-		ssh.WhatsApp = strings.TrimSpace(fields[13])
-	}
-	if len(fields) > 14 {
-		ssh.UUID = strings.TrimSpace(fields[14])
-	}
-	if len(fields) > 15 {
-		ssh.DeviceID = strings.TrimSpace(fields[15])
-	}
-	if len(fields) > 16 {
-		ssh.DeviceAtivo = strings.TrimSpace(fields[16])
-	}
+	ssh.Senha = reg.Campo("senha").String()
+	ssh.MainID = reg.Campo("mainid").String()
+	ssh.Expira = validarDataMySQL(reg.Campo("expira").String())
+	ssh.LastView = reg.Campo("lastview").Ptr()
+	ssh.Status = reg.Campo("status").String()
+	ssh.ValorMensal = reg.Campo("valormensal").String()
+	ssh.Notificado = reg.Campo("notificado").String()
+	ssh.WhatsApp = reg.Campo("whatsapp").Ptr()
+	ssh.UUID = reg.Campo("uuid").Ptr()
+	ssh.DeviceID = reg.Campo("deviceid").Ptr()
+	ssh.DeviceAtivo = reg.Campo("deviceativo").Ptr()
 
 	// Contato: número de exemplo
-	ssh.Contato = "62999999999"
+	ssh.Contato = contatoExemplo
 	// Tipo: sempre 'xray'
 	ssh.Tipo = "xray"
 	// Status: se vazio ou zero, definir como '1'
@@ -479,55 +300,41 @@ func parseSSHAccountFinal(fields []string) SSHAccountFinal {
 		ssh.MainID = "0"
 	}
 
-	return ssh
+	return ssh, nil
 }
 
-func parseAtribuidoFinal(fields []string) AtribuidoFinal {
+func parseAtribuidoFinal(reg Registro) (AtribuidoFinal, error) {
 	var atr AtribuidoFinal
-	fmt.Sscanf(fields[0], "%d", &atr.ID)
-	atr.Valor = strings.TrimSpace(fields[1])
-	fmt.Sscanf(fields[2], "%d", &atr.CategoriaID)
-	fmt.Sscanf(fields[3], "%d", &atr.UserID)
-	fmt.Sscanf(fields[4], "%d", &atr.ByID)
-	fmt.Sscanf(fields[5], "%d", &atr.Limite)
-	if len(fields) > 6 {
-		fmt.Sscanf(fields[6], "%d", &atr.LimiteTest)
-	}
-	if len(fields) > 7 {
-		atr.Tipo = strings.TrimSpace(fields[7])
-	}
-	if len(fields) > 8 {
-		atr.Expira = strings.TrimSpace(fields[8])
-	}
-	if len(fields) > 9 {
-		fmt.Sscanf(fields[9], "%d", &atr.SubRev)
-	}
-	if len(fields) > 10 {
-		var suspenso int
-		fmt.Sscanf(fields[10], "%d", &suspenso)
-		if suspenso == 0 {
-			atr.Suspenso = nil
-		} else {
-			atr.Suspenso = &suspenso
-		}
-	} else {
-		atr.Suspenso = nil
-	}
-	if len(fields) > 11 {
-		atr.ValorMensal = strings.TrimSpace(fields[11])
-	}
-	if len(fields) > 12 {
-		atr.Notificado = strings.TrimSpace(fields[12])
-	}
+	if err := reg.exigir("userid"); err != nil {
+		return atr, err
+	}
+	atr.ID = reg.Campo("id").Int()
+	atr.Valor = reg.Campo("valor").String()
+	atr.CategoriaID = reg.Campo("categoriaid").Int()
+	atr.UserID = reg.Campo("userid").Int()
+	atr.ByID = reg.Campo("byid").Int()
+	atr.Limite = reg.Campo("limite").Int()
+	atr.LimiteTest = reg.Campo("limitetest").Int()
+	atr.Tipo = reg.Campo("tipo").String()
+	atr.Expira = reg.Campo("expira").String()
+	atr.SubRev = reg.Campo("subrev").Int()
+	if suspenso := reg.Campo("suspenso").Int(); suspenso != 0 {
+		atr.Suspenso = &suspenso
+	}
+	atr.ValorMensal = reg.Campo("valormensal").String()
+	atr.Notificado = reg.Campo("notificado").String()
 	// SusID sempre nulo
 	atr.SusID = nil
-	return atr
+	return atr, nil
 }
 
-func parseCategoriaFinal(fields []string) CategoriaFinal {
+func parseCategoriaFinal(reg Registro) (CategoriaFinal, error) {
 	var cat CategoriaFinal
-	fmt.Sscanf(fields[0], "%d", &cat.ID)
-	fmt.Sscanf(fields[1], "%d", &cat.SubID)
-	cat.Nome = strings.TrimSpace(fields[2])
-	return cat
+	if err := reg.exigir("id"); err != nil {
+		return cat, err
+	}
+	cat.ID = reg.Campo("id").Int()
+	cat.SubID = reg.Campo("subid").Int()
+	cat.Nome = reg.Campo("nome").String()
+	return cat, nil
 }
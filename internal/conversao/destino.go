@@ -0,0 +1,278 @@
+package conversao
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// TabelaDestino é uma tabela do painel de destino já com as linhas prontas
+// para gravar, com ids, byid e mainid resolvidos. É a forma comum usada
+// tanto pela carga no MySQL quanto pela escrita direta do dump.
+type TabelaDestino struct {
+	Nome      string
+	Definicao string // colunas e opções do CREATE TABLE, sem o nome
+	Colunas   []string
+	Linhas    [][]interface{}
+}
+
+// definicoesDestino são as estruturas das tabelas do painel de destino
+var definicoesDestino = map[string]string{
+	"accounts": `(
+		id INT PRIMARY KEY AUTO_INCREMENT,
+		nome VARCHAR(255),
+		contato VARCHAR(255),
+		email VARCHAR(255),
+		login VARCHAR(255),
+		senha VARCHAR(255),
+		recuperar_senha VARCHAR(255),
+		byid INT,
+		mainid INT,
+		accesstoken INT,
+		valorrevenda DECIMAL(10,2),
+		valorusuario DECIMAL(10,2),
+		nivel INT
+	) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci`,
+	"categorias": `(
+		id INT PRIMARY KEY AUTO_INCREMENT,
+		subid INT,
+		nome VARCHAR(255)
+	) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci`,
+	"ssh_accounts": `(
+		id INT PRIMARY KEY AUTO_INCREMENT,
+		login VARCHAR(255),
+		senha VARCHAR(255),
+		nome VARCHAR(255),
+		expira DATETIME,
+		categoriaid INT,
+		limite INT,
+		contato VARCHAR(255),
+		uuid VARCHAR(255),
+		whatsapp VARCHAR(255),
+		deviceid VARCHAR(255),
+		nivel INT,
+		byid INT,
+		mainid INT
+	) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci`,
+	"atribuidos": `(
+		id INT PRIMARY KEY AUTO_INCREMENT,
+		valor DECIMAL(10,2),
+		categoriaid INT,
+		userid INT,
+		byid INT,
+		limite INT,
+		limitetest INT,
+		tipo VARCHAR(255),
+		expira DATETIME,
+		subrev INT,
+		suspenso INT
+	) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci`,
+}
+
+// TabelasDe monta as tabelas de destino de dados já transformados, como
+// *DatabaseExport e *DatabaseFinal
+func TabelasDe(dados Dados) ([]TabelaDestino, error) {
+	d, ok := dados.(interface{ TabelasDestino() []TabelaDestino })
+	if !ok {
+		return nil, fmt.Errorf("dados sem tabelas de destino: %T", dados)
+	}
+	return d.TabelasDestino(), nil
+}
+
+func novaTabelaDestino(nome string, colunas ...string) *TabelaDestino {
+	return &TabelaDestino{Nome: nome, Definicao: definicoesDestino[nome], Colunas: colunas}
+}
+
+func (t *TabelaDestino) adicionar(valores ...interface{}) {
+	t.Linhas = append(t.Linhas, valores)
+}
+
+// TabelasDestino monta as tabelas do painel de destino a partir dos dados
+// convertidos do Eclipse. Os ids são atribuídos aqui, e não pelo
+// AUTO_INCREMENT, para que byid e mainid possam ser resolvidos de antemão.
+func (dbExport *DatabaseExport) TabelasDestino() []TabelaDestino {
+	categorias := novaTabelaDestino("categorias", "id", "subid", "nome")
+	accounts := novaTabelaDestino("accounts", "id", "nome", "contato", "email", "login", "senha",
+		"recuperar_senha", "byid", "mainid", "accesstoken", "valorrevenda", "valorusuario", "nivel")
+	atribuidos := novaTabelaDestino("atribuidos", "id", "valor", "categoriaid", "userid", "byid",
+		"limite", "limitetest", "tipo", "expira", "subrev", "suspenso")
+	sshAccounts := novaTabelaDestino("ssh_accounts", "id", "login", "senha", "nome", "expira",
+		"categoriaid", "limite", "contato", "uuid", "nivel", "byid", "mainid")
+
+	// Admin
+	const adminID int64 = 1
+	accounts.adicionar(adminID, "Admin", contatoExemplo, "admin@admin.com", "admin", "admin",
+		nil, 0, 0, 0, 0.00, 0.00, 3)
+	proximaConta := adminID + 1
+
+	// Mapear logins para IDs para preencher byid corretamente
+	loginToID := map[string]int64{"admin": adminID}
+	loginToMainID := map[string]int64{"admin": 0}
+
+	for i, cat := range dbExport.Categorias {
+		categorias.adicionar(i+1, cat.SubID, strings.TrimSpace(cat.Nome))
+	}
+
+	// Revendas vão para accounts e atribuidos
+	for _, rev := range dbExport.Revendas {
+		byid := adminID // valor padrão
+		if rev.Dono != "admin" && rev.Dono != "desconhecido" {
+			if donoID, ok := loginToID[rev.Dono]; ok {
+				byid = donoID
+			}
+		}
+
+		mainid := int64(GerarMainID()) // Sempre gera um novo hash para cada revenda
+
+		revendaID := proximaConta
+		proximaConta++
+
+		accounts.adicionar(
+			revendaID,
+			strings.TrimSpace(rev.Nome),
+			strings.TrimSpace(rev.Contato),
+			strings.TrimSpace(rev.Email),
+			strings.TrimSpace(rev.Login),
+			strings.TrimSpace(rev.Senha),
+			nil,
+			byid,
+			mainid,
+			0, 0, 0, 2,
+		)
+		loginToID[rev.Login] = revendaID
+		loginToMainID[rev.Login] = mainid
+
+		atribuidos.adicionar(
+			revendaID-adminID,
+			rev.Valor,
+			rev.CategoriaID,
+			revendaID,
+			byid,
+			rev.Limite,
+			rev.Limite,
+			cases.Title(language.Und, cases.NoLower).String(strings.TrimSpace(strings.ToLower(rev.Tipo))),
+			strings.TrimSpace(rev.Expira),
+			rev.Sub,
+			nil,
+		)
+	}
+
+	// Usuários vão para ssh_accounts
+	for i, user := range dbExport.Usuarios {
+		var donoID int64 = 0
+		var mainid int64 = 0
+		if id, ok := loginToID[strings.TrimSpace(user.Dono)]; ok {
+			donoID = id
+		}
+		if mid, ok := loginToMainID[strings.TrimSpace(user.Dono)]; ok {
+			mainid = mid // herda o mainid da revenda
+		} else {
+			mainid = int64(GerarMainID()) // fallback se não encontrar dono
+		}
+
+		nome := strings.TrimSpace(user.Nome)
+		if nome == "" {
+			nome = strings.TrimSpace(user.Login)
+		}
+
+		// UUID ausente, vazio ou "0" vira NULL de verdade
+		var uuid interface{}
+		if user.UUID != nil && *user.UUID != "" && *user.UUID != "0" {
+			uuid = *user.UUID
+		}
+
+		sshAccounts.adicionar(
+			i+1,
+			strings.TrimSpace(user.Login),
+			strings.TrimSpace(user.Senha),
+			nome,
+			strings.TrimSpace(user.Expira),
+			user.CategoriaID,
+			user.Limite,
+			strings.TrimSpace(user.Contato),
+			uuid,
+			1,
+			donoID,
+			mainid,
+		)
+	}
+
+	return []TabelaDestino{*categorias, *accounts, *atribuidos, *sshAccounts}
+}
+
+// TabelasDestino monta as tabelas a partir de um dump no formato final,
+// mantendo os ids do dump
+func (dbFinal *DatabaseFinal) TabelasDestino() []TabelaDestino {
+	categorias := novaTabelaDestino("categorias", "id", "subid", "nome")
+	accounts := novaTabelaDestino("accounts", "id", "nome", "contato", "email", "login", "senha",
+		"byid", "mainid", "nivel")
+	sshAccounts := novaTabelaDestino("ssh_accounts", "id", "byid", "categoriaid", "limite", "login",
+		"nome", "senha", "mainid", "expira", "uuid", "whatsapp", "deviceid", "contato")
+	atribuidos := novaTabelaDestino("atribuidos", "id", "valor", "categoriaid", "userid", "byid",
+		"limite", "limitetest", "tipo", "expira", "subrev", "suspenso")
+
+	for _, cat := range dbFinal.Categorias {
+		categorias.adicionar(cat.ID, cat.SubID, strings.TrimSpace(cat.Nome))
+	}
+
+	for _, acc := range dbFinal.Accounts {
+		accounts.adicionar(
+			acc.ID,
+			strings.TrimSpace(acc.Nome),
+			strings.TrimSpace(acc.Contato),
+			strings.TrimSpace(acc.Email),
+			strings.TrimSpace(acc.Login),
+			strings.TrimSpace(acc.Senha),
+			strings.TrimSpace(acc.ByID),
+			strings.TrimSpace(acc.MainID),
+			acc.Nivel,
+		)
+	}
+
+	for _, ssh := range dbFinal.SSHAccounts {
+		sshAccounts.adicionar(
+			ssh.ID,
+			ssh.ByID,
+			ssh.CategoriaID,
+			ssh.Limite,
+			strings.TrimSpace(ssh.Login),
+			strings.TrimSpace(ssh.Nome),
+			strings.TrimSpace(ssh.Senha),
+			strings.TrimSpace(ssh.MainID),
+			dataOuNulo(ssh.Expira),
+			ssh.UUID,
+			ssh.WhatsApp,
+			ssh.DeviceID,
+			strings.TrimSpace(ssh.Contato),
+		)
+	}
+
+	for _, atr := range dbFinal.Atribuidos {
+		atribuidos.adicionar(
+			atr.ID,
+			strings.TrimSpace(atr.Valor),
+			atr.CategoriaID,
+			atr.UserID,
+			atr.ByID,
+			atr.Limite,
+			atr.LimiteTest,
+			strings.TrimSpace(atr.Tipo),
+			dataOuNulo(atr.Expira),
+			atr.SubRev,
+			atr.Suspenso,
+		)
+	}
+
+	return []TabelaDestino{*categorias, *accounts, *sshAccounts, *atribuidos}
+}
+
+// dataOuNulo converte datas vazias ou zeradas em NULL
+func dataOuNulo(data string) interface{} {
+	data = strings.TrimSpace(data)
+	if data == "" || data == "0000-00-00 00:00:00" || strings.EqualFold(data, "NULL") {
+		return nil
+	}
+	return data
+}
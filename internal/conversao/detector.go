@@ -0,0 +1,141 @@
+package conversao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DetectarFormato identifica o conversor cujo formato de origem melhor
+// corresponde ao dump, comparando as tabelas e colunas declaradas (CREATE
+// TABLE e listas de colunas dos INSERT) com as de cada conversor
+// registrado. Retorna nil quando nenhum formato é reconhecido ou quando há
+// empate. Só o início do dump é lido (ver tabelasDoDump), e a leitura para
+// se ctx for cancelado.
+func DetectarFormato(ctx context.Context, entrada Entrada) (Converter, error) {
+	file, err := AbrirEntrada(entrada)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var formatos []map[string][]string
+	for _, conv := range Conversores() {
+		formatos = append(formatos, conv.Tabelas())
+	}
+	encontradas, err := tabelasDoDump(leitorComContexto(ctx, file), formatos)
+	if err != nil {
+		return nil, err
+	}
+
+	var melhor Converter
+	var melhorPontos float64
+	empate := false
+	for _, conv := range Conversores() {
+		pontos := pontuarFormato(encontradas, conv.Tabelas())
+		switch {
+		case pontos > melhorPontos:
+			melhor, melhorPontos, empate = conv, pontos, false
+		case pontos == melhorPontos && pontos > 0:
+			empate = true
+		}
+	}
+	if empate {
+		return nil, nil
+	}
+	return melhor, nil
+}
+
+// limiteInstrucoesDeteccao é quantos CREATE TABLE e INSERT a detecção lê,
+// no máximo. Dumps declaram cada tabela antes dos dados dela, então o início
+// do dump já mostra quais tabelas ele tem.
+const limiteInstrucoesDeteccao = 200
+
+// tabelasDoDump lista as tabelas com CREATE TABLE ou INSERT no dump e as
+// colunas conhecidas de cada uma. Instruções defeituosas são ignoradas. A
+// leitura para quando todas as tabelas de algum dos formatos apareceram ou
+// após limiteInstrucoesDeteccao instruções: o resto do dump dificilmente
+// mudaria a detecção, e ler os registros de um dump grande até o fim
+// custaria quase tanto quanto a conversão.
+func tabelasDoDump(r io.Reader, formatos []map[string][]string) (map[string]map[string]bool, error) {
+	leitor := NovoLeitorSQL(r)
+	tabelas := make(map[string]map[string]bool)
+
+	for lidas := 0; lidas < limiteInstrucoesDeteccao && !algumFormatoCompleto(tabelas, formatos); {
+		inst, err := leitor.Proxima()
+		if err == io.EOF {
+			return tabelas, nil
+		}
+		var erroParse *ErroParse
+		if errors.As(err, &erroParse) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler o dump: %v", err)
+		}
+
+		var colunas []string
+		switch inst.Tipo {
+		case InstrucaoCreateTable:
+			colunas = inst.Create.NomesColunas()
+		case InstrucaoInsert:
+			colunas = inst.Colunas
+		default:
+			continue
+		}
+		lidas++
+
+		nome := strings.ToLower(inst.Tabela)
+		if tabelas[nome] == nil {
+			tabelas[nome] = make(map[string]bool)
+		}
+		for _, c := range colunas {
+			tabelas[nome][strings.ToLower(c)] = true
+		}
+	}
+	return tabelas, nil
+}
+
+// algumFormatoCompleto informa se todas as tabelas de algum formato já
+// foram encontradas
+func algumFormatoCompleto(encontradas map[string]map[string]bool, formatos []map[string][]string) bool {
+	for _, esperadas := range formatos {
+		completo := len(esperadas) > 0
+		for tabela := range esperadas {
+			if _, ok := encontradas[tabela]; !ok {
+				completo = false
+				break
+			}
+		}
+		if completo {
+			return true
+		}
+	}
+	return false
+}
+
+// pontuarFormato soma um ponto por tabela esperada presente no dump, mais a
+// fração das colunas declaradas que o formato reconhece
+func pontuarFormato(encontradas map[string]map[string]bool, esperadas map[string][]string) float64 {
+	var pontos float64
+	for tabela, colunas := range esperadas {
+		declaradas, ok := encontradas[tabela]
+		if !ok {
+			continue
+		}
+		pontos++
+		if len(declaradas) == 0 {
+			continue
+		}
+		conhecidas := 0
+		for _, c := range colunas {
+			if declaradas[c] {
+				conhecidas++
+			}
+		}
+		pontos += float64(conhecidas) / float64(len(declaradas))
+	}
+	return pontos
+}
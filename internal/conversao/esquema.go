@@ -0,0 +1,312 @@
+package conversao
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Esquema reúne as tabelas que o dump declara, seja por CREATE TABLE ou
+// pelas listas de colunas dos INSERTs
+type Esquema struct {
+	Tabelas map[string]*TabelaEsquema
+}
+
+// TabelaEsquema descreve as colunas de uma tabela do dump de origem
+type TabelaEsquema struct {
+	Nome    string
+	Colunas []ColunaEsquema
+}
+
+// ColunaEsquema descreve uma coluna; Tipo fica vazio quando a coluna só
+// foi vista na lista de um INSERT
+type ColunaEsquema struct {
+	Nome   string
+	Tipo   string // ex.: "int(11)", "varchar(255)", "datetime"
+	Nula   bool
+	Padrao *Valor // nil quando não há DEFAULT
+}
+
+// NovoEsquema cria um esquema vazio
+func NovoEsquema() *Esquema {
+	return &Esquema{Tabelas: make(map[string]*TabelaEsquema)}
+}
+
+// Tabela retorna a definição da tabela, ou nil se o dump não a declarou
+func (e *Esquema) Tabela(nome string) *TabelaEsquema {
+	return e.Tabelas[strings.ToLower(nome)]
+}
+
+// adicionar registra a definição de uma tabela, substituindo a anterior
+func (e *Esquema) adicionar(t *TabelaEsquema) {
+	e.Tabelas[strings.ToLower(t.Nome)] = t
+}
+
+// registrarColunas guarda as colunas de um INSERT para tabelas sem CREATE
+// TABLE no dump
+func (e *Esquema) registrarColunas(tabela string, colunas []string) {
+	t := e.Tabela(tabela)
+	if t == nil {
+		t = &TabelaEsquema{Nome: tabela}
+		e.adicionar(t)
+	}
+	for _, c := range colunas {
+		if t.Coluna(c) == nil {
+			t.Colunas = append(t.Colunas, ColunaEsquema{Nome: c, Nula: true})
+		}
+	}
+}
+
+// ColunasDesconhecidas lista, por tabela, as colunas do dump que não fazem
+// parte das colunas conhecidas pelo conversor. Tabelas que o conversor não
+// usa são ignoradas.
+func (e *Esquema) ColunasDesconhecidas(conhecidas map[string][]string) map[string][]string {
+	resultado := make(map[string][]string)
+	for nome, t := range e.Tabelas {
+		colunas, ok := conhecidas[nome]
+		if !ok {
+			continue
+		}
+		mapa := novoMapaColunas(colunas)
+		for _, c := range t.Colunas {
+			if _, ok := mapa[strings.ToLower(c.Nome)]; !ok {
+				resultado[nome] = append(resultado[nome], c.Nome)
+			}
+		}
+	}
+	return resultado
+}
+
+// NomesColunas retorna os nomes das colunas na ordem declarada
+func (t *TabelaEsquema) NomesColunas() []string {
+	nomes := make([]string, len(t.Colunas))
+	for i, c := range t.Colunas {
+		nomes[i] = c.Nome
+	}
+	return nomes
+}
+
+// Coluna retorna a definição da coluna, ou nil se não existir
+func (t *TabelaEsquema) Coluna(nome string) *ColunaEsquema {
+	for i := range t.Colunas {
+		if strings.EqualFold(t.Colunas[i].Nome, nome) {
+			return &t.Colunas[i]
+		}
+	}
+	return nil
+}
+
+// coagir ajusta um valor lido do dump ao tipo declarado da coluna:
+// números vazios viram 0 (ou NULL, se a coluna aceitar), literais binários
+// em colunas numéricas viram inteiros e datas zeradas viram NULL
+func (c *ColunaEsquema) coagir(v Valor) Valor {
+	if v.Nulo || c.Tipo == "" {
+		return v
+	}
+	tipo := strings.ToLower(c.Tipo)
+	switch {
+	case isTipoNumerico(tipo):
+		texto := strings.TrimSpace(v.Texto)
+		if texto == "" {
+			if c.Nula {
+				return Valor{Nulo: true}
+			}
+			return Valor{Texto: "0"}
+		}
+		if strings.HasPrefix(tipo, "bit") && !isNumeroDecimal(texto) {
+			// b'1' e 0x01 chegam como bytes crus
+			var n uint64
+			for i := 0; i < len(v.Texto); i++ {
+				n = n<<8 | uint64(v.Texto[i])
+			}
+			return Valor{Texto: strconv.FormatUint(n, 10)}
+		}
+	case strings.HasPrefix(tipo, "date") || strings.HasPrefix(tipo, "timestamp"):
+		if strings.HasPrefix(strings.TrimSpace(v.Texto), "0000-00-00") {
+			return Valor{Nulo: true}
+		}
+	}
+	return v
+}
+
+// padrao retorna o valor usado quando a coluna não aparece no INSERT
+func (c *ColunaEsquema) padrao() Valor {
+	if c.Padrao == nil {
+		return Valor{Nulo: true}
+	}
+	return c.coagir(*c.Padrao)
+}
+
+func isTipoNumerico(tipo string) bool {
+	for _, prefixo := range []string{"tinyint", "smallint", "mediumint", "int", "bigint", "integer",
+		"decimal", "numeric", "float", "double", "real", "bit", "bool"} {
+		if strings.HasPrefix(tipo, prefixo) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeroDecimal(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// lerCreateTable interpreta um CREATE TABLE a partir da palavra TABLE,
+// extraindo nome, tipo, nulidade e DEFAULT de cada coluna. Chaves, índices
+// e restrições são ignorados; as opções após a lista de colunas ficam no
+// lexer para quem chamou.
+func lerCreateTable(lx *Lexer) (*TabelaEsquema, error) {
+	tabela, err := lerNomeTabela(lx, "TABLE")
+	if err != nil {
+		return nil, err
+	}
+	// IF NOT EXISTS
+	if strings.EqualFold(tabela, "IF") {
+		for i := 0; i < 2; i++ {
+			lx.Proximo()
+		}
+		if tabela, err = lerIdentificador(lx); err != nil {
+			return nil, err
+		}
+		if lx.espiar() == '.' {
+			lx.Proximo()
+			if tabela, err = lerIdentificador(lx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tok, err := lx.Proximo()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Tipo != TokenSimbolo || tok.Texto != "(" {
+		// CREATE TABLE ... LIKE / AS SELECT: sem colunas declaradas
+		lx.devolver(tok)
+		return &TabelaEsquema{Nome: tabela}, nil
+	}
+
+	t := &TabelaEsquema{Nome: tabela}
+	for {
+		definicao, fim, err := lerDefinicao(lx)
+		if err != nil {
+			return nil, err
+		}
+		if len(definicao) > 0 && isDefinicaoDeColuna(definicao[0]) {
+			t.Colunas = append(t.Colunas, parsearColuna(definicao))
+		}
+		if fim {
+			return t, nil
+		}
+	}
+}
+
+// parsearColuna monta a definição de uma coluna a partir dos seus tokens,
+// por exemplo: `id` int(11) unsigned NOT NULL DEFAULT '0'
+func parsearColuna(tokens []Token) ColunaEsquema {
+	col := ColunaEsquema{Nome: tokens[0].Texto, Nula: true}
+	i := 1
+	if i < len(tokens) && tokens[i].Tipo == TokenPalavra {
+		var tipo strings.Builder
+		tipo.WriteString(strings.ToLower(tokens[i].Texto))
+		i++
+		// argumentos do tipo: (11), (10,2), enum('a','b')
+		if i < len(tokens) && tokens[i].Tipo == TokenSimbolo && tokens[i].Texto == "(" {
+			var args []string
+			for i++; i < len(tokens) && !(tokens[i].Tipo == TokenSimbolo && tokens[i].Texto == ")"); i++ {
+				if tokens[i].Tipo != TokenSimbolo {
+					args = append(args, tokens[i].Texto)
+				}
+			}
+			i++
+			tipo.WriteString("(" + strings.Join(args, ",") + ")")
+		}
+		for ; i < len(tokens) && tokens[i].Tipo == TokenPalavra; i++ {
+			p := strings.ToLower(tokens[i].Texto)
+			if p != "unsigned" && p != "signed" && p != "zerofill" {
+				break
+			}
+			tipo.WriteString(" " + p)
+		}
+		col.Tipo = tipo.String()
+	}
+
+	for ; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.Tipo != TokenPalavra {
+			continue
+		}
+		switch strings.ToUpper(tok.Texto) {
+		case "NOT":
+			if i+1 < len(tokens) && strings.EqualFold(tokens[i+1].Texto, "NULL") {
+				col.Nula = false
+				i++
+			}
+		case "DEFAULT":
+			if i+1 >= len(tokens) {
+				continue
+			}
+			i++
+			padrao := tokens[i]
+			switch {
+			case padrao.Tipo == TokenPalavra && strings.EqualFold(padrao.Texto, "NULL"):
+				col.Padrao = &Valor{Nulo: true}
+			case padrao.Tipo == TokenSimbolo && padrao.Texto == "-" && i+1 < len(tokens):
+				i++
+				col.Padrao = &Valor{Texto: "-" + tokens[i].Texto}
+			case padrao.Tipo == TokenSimbolo:
+				// expressões como DEFAULT (uuid()) não têm valor fixo
+			default:
+				col.Padrao = &Valor{Texto: padrao.Texto}
+			}
+		}
+	}
+	return col
+}
+
+// lerDefinicao lê os tokens de uma definição do CREATE TABLE até a vírgula
+// de nível superior. fim indica que o ')' que fecha a lista foi consumido.
+func lerDefinicao(lx *Lexer) ([]Token, bool, error) {
+	var tokens []Token
+	nivel := 0
+	for {
+		tok, err := lx.Proximo()
+		if err != nil {
+			return nil, false, err
+		}
+		if tok.Tipo == TokenFim {
+			return nil, false, fmt.Errorf("CREATE TABLE não terminado")
+		}
+		if tok.Tipo == TokenSimbolo {
+			switch tok.Texto {
+			case "(":
+				nivel++
+			case ")":
+				if nivel == 0 {
+					return tokens, true, nil
+				}
+				nivel--
+			case ",":
+				if nivel == 0 {
+					return tokens, false, nil
+				}
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func isDefinicaoDeColuna(tok Token) bool {
+	if tok.Tipo == TokenIdentificador {
+		return true
+	}
+	if tok.Tipo != TokenPalavra {
+		return false
+	}
+	switch strings.ToUpper(tok.Texto) {
+	case "PRIMARY", "KEY", "UNIQUE", "INDEX", "CONSTRAINT", "FOREIGN", "FULLTEXT", "SPATIAL", "CHECK":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package conversao
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEsquemaCreateTable(t *testing.T) {
+	sql := "CREATE TABLE IF NOT EXISTS `painel`.`Contas` (\n" +
+		"  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `nome` varchar(255) DEFAULT 'sem nome',\n" +
+		"  `saldo` decimal(10,2) NOT NULL DEFAULT '0.00',\n" +
+		"  `ajuste` int DEFAULT -1,\n" +
+		"  `obs` text DEFAULT NULL,\n" +
+		"  `uid` char(36) DEFAULT (uuid()),\n" +
+		"  `tipo` enum('a','b') DEFAULT 'a' COMMENT 'tipo, com vírgula',\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_nome` (`nome`),\n" +
+		"  CONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `u` (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;"
+
+	_, esquema, _ := lerSQL(t, sql, nil)
+	tabela := esquema.Tabela("contas")
+	if tabela == nil {
+		t.Fatalf("tabela não encontrada no esquema: %v", esquema.Tabelas)
+	}
+
+	esperado := []string{
+		"id int(11) unsigned NOT NULL",
+		"nome varchar(255) DEFAULT 'sem nome'",
+		"saldo decimal(10,2) NOT NULL DEFAULT '0.00'",
+		"ajuste int DEFAULT '-1'",
+		"obs text DEFAULT NULL",
+		"uid char(36)",
+		"tipo enum(a,b) DEFAULT 'a'",
+	}
+	var obtido []string
+	for _, c := range tabela.Colunas {
+		d := c.Nome + " " + c.Tipo
+		if !c.Nula {
+			d += " NOT NULL"
+		}
+		switch {
+		case c.Padrao == nil:
+		case c.Padrao.Nulo:
+			d += " DEFAULT NULL"
+		default:
+			d += fmt.Sprintf(" DEFAULT '%s'", c.Padrao.Texto)
+		}
+		obtido = append(obtido, d)
+	}
+	if strings.Join(obtido, "\n") != strings.Join(esperado, "\n") {
+		t.Errorf("colunas:\n%s\nesperado:\n%s", strings.Join(obtido, "\n"), strings.Join(esperado, "\n"))
+	}
+}
+
+func TestColunaCoagir(t *testing.T) {
+	nulo := Valor{Nulo: true}
+	casos := []struct {
+		nome     string
+		coluna   ColunaEsquema
+		valor    Valor
+		esperado Valor
+	}{
+		{"número vazio em coluna anulável", ColunaEsquema{Tipo: "int(11)", Nula: true}, Valor{Texto: ""}, nulo},
+		{"número vazio em coluna NOT NULL", ColunaEsquema{Tipo: "int(11)"}, Valor{Texto: " "}, Valor{Texto: "0"}},
+		{"decimal vazio", ColunaEsquema{Tipo: "decimal(10,2)"}, Valor{Texto: ""}, Valor{Texto: "0"}},
+		{"número preenchido", ColunaEsquema{Tipo: "bigint"}, Valor{Texto: "42"}, Valor{Texto: "42"}},
+		{"bit em bytes", ColunaEsquema{Tipo: "bit(1)"}, Valor{Texto: "\x01"}, Valor{Texto: "1"}},
+		{"bit de vários bytes", ColunaEsquema{Tipo: "bit(16)"}, Valor{Texto: "\x01\x00"}, Valor{Texto: "256"}},
+		{"bit já decimal", ColunaEsquema{Tipo: "bit(8)"}, Valor{Texto: "5"}, Valor{Texto: "5"}},
+		{"datetime zerado", ColunaEsquema{Tipo: "datetime"}, Valor{Texto: "0000-00-00 00:00:00"}, nulo},
+		{"date zerada", ColunaEsquema{Tipo: "date"}, Valor{Texto: "0000-00-00"}, nulo},
+		{"timestamp válido", ColunaEsquema{Tipo: "timestamp"}, Valor{Texto: "2024-01-02 03:04:05"}, Valor{Texto: "2024-01-02 03:04:05"}},
+		{"texto vazio fica vazio", ColunaEsquema{Tipo: "varchar(10)"}, Valor{Texto: ""}, Valor{Texto: ""}},
+		{"NULL continua NULL", ColunaEsquema{Tipo: "int"}, nulo, nulo},
+		{"coluna sem tipo não muda", ColunaEsquema{}, Valor{Texto: ""}, Valor{Texto: ""}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if obtido := c.coluna.coagir(c.valor); obtido != c.esperado {
+				t.Errorf("coagir(%q) = %+v, esperado %+v", c.valor.Texto, obtido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestRegistroPadraoDoCreate(t *testing.T) {
+	sql := "CREATE TABLE t (\n" +
+		"  a int NOT NULL,\n" +
+		"  b int NOT NULL DEFAULT '',\n" +
+		"  c datetime DEFAULT '0000-00-00 00:00:00',\n" +
+		"  d varchar(5) DEFAULT 'x',\n" +
+		"  e int\n" +
+		");\n" +
+		"INSERT INTO t (a, e) VALUES ('', '');"
+
+	lidos, _, _ := lerSQL(t, sql, nil)
+	// Valores e DEFAULTs passam pela coerção do tipo da coluna
+	esperado := []string{"t: a=0 b=0 c=NULL d=x e=NULL f=NULL"}
+	if obtido := descrever(lidos, "a", "b", "c", "d", "e", "f"); fmt.Sprint(obtido) != fmt.Sprint(esperado) {
+		t.Errorf("registros:\n obtido   %q\n esperado %q", obtido, esperado)
+	}
+}
+
+func TestColunasDesconhecidas(t *testing.T) {
+	sql := "CREATE TABLE t (a int, B int, extra int);\n" +
+		"CREATE TABLE ignorada (x int);\n" +
+		"INSERT INTO u (a, nova) VALUES (1, 2);"
+	_, esquema, _ := lerSQL(t, sql, nil)
+
+	conhecidas := map[string][]string{"t": {"a", "b"}, "u": {"a"}}
+	obtido := esquema.ColunasDesconhecidas(conhecidas)
+	esperado := map[string][]string{"t": {"extra"}, "u": {"nova"}}
+	if fmt.Sprint(obtido) != fmt.Sprint(esperado) {
+		t.Errorf("ColunasDesconhecidas = %v, esperado %v", obtido, esperado)
+	}
+}
@@ -0,0 +1,85 @@
+package conversao
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	Registrar(conversorEclipse{})
+	Registrar(conversorAtlas{})
+}
+
+// Diagnostico implementa Dados
+func (db *Database) Diagnostico() *RelatorioParse { return &db.Relatorio }
+
+// Diagnostico implementa Dados
+func (db *DatabaseExport) Diagnostico() *RelatorioParse { return &db.Relatorio }
+
+// Diagnostico implementa Dados
+func (db *DatabaseFinal) Diagnostico() *RelatorioParse { return &db.Relatorio }
+
+// conversorEclipse converte dumps do painel Eclipse para o banco do Atlas
+type conversorEclipse struct{}
+
+func (conversorEclipse) Origem() string  { return "eclipse" }
+func (conversorEclipse) Destino() string { return "atlas" }
+func (conversorEclipse) Nome() string    { return "Eclipse" }
+
+func (conversorEclipse) Tabelas() map[string][]string { return colunasEclipse }
+
+func (conversorEclipse) Ler(ctx context.Context, entrada Entrada, acomp *Acompanhamento) (Dados, error) {
+	return LerArquivoSQL(ctx, entrada, acomp)
+}
+
+func (conversorEclipse) Transformar(dados Dados) (Dados, error) {
+	db, ok := dados.(*Database)
+	if !ok {
+		return nil, fmt.Errorf("dados inesperados para o Eclipse: %T", dados)
+	}
+	return TransformarDatabase(db), nil
+}
+
+func (c conversorEclipse) Carregar(ctx context.Context, dados Dados, dsn string, acomp *Acompanhamento) error {
+	return carregar(ctx, c, dados, dsn, acomp)
+}
+
+func (conversorEclipse) Simular(lidos Dados) *Simulacao {
+	if db, ok := lidos.(*Database); ok {
+		return simularEclipse(db)
+	}
+	return &Simulacao{}
+}
+
+// conversorAtlas recarrega dumps que já estão no formato do Atlas
+type conversorAtlas struct{}
+
+func (conversorAtlas) Origem() string  { return "atlas" }
+func (conversorAtlas) Destino() string { return "atlas" }
+func (conversorAtlas) Nome() string    { return "Atlas" }
+
+func (conversorAtlas) Tabelas() map[string][]string { return colunasFinal }
+
+func (conversorAtlas) Ler(ctx context.Context, entrada Entrada, acomp *Acompanhamento) (Dados, error) {
+	return LerArquivoSQLFinal(ctx, entrada, acomp)
+}
+
+func (conversorAtlas) Transformar(dados Dados) (Dados, error) {
+	db, ok := dados.(*DatabaseFinal)
+	if !ok {
+		return nil, fmt.Errorf("dados inesperados para o Atlas: %T", dados)
+	}
+	AjustarMainIDs(db)
+	return db, nil
+}
+
+func (c conversorAtlas) Carregar(ctx context.Context, dados Dados, dsn string, acomp *Acompanhamento) error {
+	return carregar(ctx, c, dados, dsn, acomp)
+}
+
+func (conversorAtlas) Simular(lidos Dados) *Simulacao {
+	if db, ok := lidos.(*DatabaseFinal); ok {
+		return simularFinal(db)
+	}
+	return &Simulacao{}
+}
@@ -0,0 +1,107 @@
+package conversao
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Registro associa os valores de uma linha aos nomes das colunas. Quando o
+// dump declara a tabela, os valores são ajustados ao tipo de cada coluna.
+type Registro struct {
+	colunas map[string]int
+	valores []Valor
+	tabela  *TabelaEsquema
+}
+
+// novoMapaColunas indexa os nomes das colunas (sem diferenciar maiúsculas)
+// pela posição em que aparecem
+func novoMapaColunas(colunas []string) map[string]int {
+	m := make(map[string]int, len(colunas))
+	for i, c := range colunas {
+		m[strings.ToLower(c)] = i
+	}
+	return m
+}
+
+// Campo retorna o valor da coluna. Colunas ausentes assumem o DEFAULT do
+// CREATE TABLE ou, sem ele, NULL.
+func (r Registro) Campo(coluna string) Valor {
+	var def *ColunaEsquema
+	if r.tabela != nil {
+		def = r.tabela.Coluna(coluna)
+	}
+	i, ok := r.colunas[strings.ToLower(coluna)]
+	if !ok || i >= len(r.valores) {
+		if def != nil {
+			return def.padrao()
+		}
+		return Valor{Nulo: true}
+	}
+	if def != nil {
+		return def.coagir(r.valores[i])
+	}
+	return r.valores[i]
+}
+
+// exigir verifica se as colunas obrigatórias estão presentes e não são NULL
+func (r Registro) exigir(colunas ...string) error {
+	for _, c := range colunas {
+		if r.Campo(c).String() == "" {
+			return fmt.Errorf("coluna obrigatória %s ausente, vazia ou NULL", c)
+		}
+	}
+	return nil
+}
+
+// Tem informa se a coluna está presente no registro
+func (r Registro) Tem(coluna string) bool {
+	i, ok := r.colunas[strings.ToLower(coluna)]
+	return ok && i < len(r.valores)
+}
+
+// lerNomeTabela avança até a palavra-chave informada e lê o nome de tabela
+// que a segue, descartando um eventual prefixo "banco.". A busca não passa
+// do fim da instrução.
+func lerNomeTabela(lx *Lexer, palavra string) (string, error) {
+	for {
+		tok, err := lx.Proximo()
+		if err != nil {
+			return "", err
+		}
+		if tok.Tipo == TokenFim || (tok.Tipo == TokenSimbolo && tok.Texto == ";") {
+			lx.devolver(tok)
+			return "", fmt.Errorf("palavra-chave %s não encontrada", palavra)
+		}
+		if tok.Tipo == TokenPalavra && strings.EqualFold(tok.Texto, palavra) {
+			break
+		}
+	}
+	return lerNomeQualificado(lx)
+}
+
+// lerNomeQualificado lê um nome de tabela, descartando um eventual prefixo
+// "banco."
+func lerNomeQualificado(lx *Lexer) (string, error) {
+	nome, err := lerIdentificador(lx)
+	if err != nil {
+		return "", err
+	}
+	// banco.tabela
+	if prox := lx.espiar(); prox == '.' {
+		lx.Proximo()
+		return lerIdentificador(lx)
+	}
+	return nome, nil
+}
+
+func lerIdentificador(lx *Lexer) (string, error) {
+	tok, err := lx.Proximo()
+	if err != nil {
+		return "", err
+	}
+	if tok.Tipo != TokenPalavra && tok.Tipo != TokenIdentificador {
+		lx.devolver(tok)
+		return "", fmt.Errorf("esperado identificador, encontrado %q", tok.Texto)
+	}
+	return tok.Texto, nil
+}
@@ -0,0 +1,434 @@
+package conversao
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TipoInstrucao classifica as instruções de um dump
+type TipoInstrucao int
+
+const (
+	InstrucaoOutra TipoInstrucao = iota
+	InstrucaoInsert
+	InstrucaoCreateTable
+)
+
+// Instrucao descreve uma instrução do dump. Os registros de um INSERT não
+// ficam aqui: são lidos um a um com LeitorSQL.ProximoRegistro.
+type Instrucao struct {
+	Tipo    TipoInstrucao
+	Numero  int            // posição da instrução no dump, a partir de 1
+	Tabela  string         // INSERT e CREATE TABLE
+	Colunas []string       // lista explícita do INSERT, quando houver
+	Create  *TabelaEsquema // definição lida de um CREATE TABLE
+}
+
+// LeitorSQL lê um dump instrução por instrução. As instruções são separadas
+// por ';' fora de textos e comentários, independente das quebras de linha,
+// e só um registro de INSERT fica em memória por vez.
+type LeitorSQL struct {
+	lx       *Lexer
+	numero   int
+	emInsert bool // há registros do INSERT corrente ainda não lidos
+
+	atual      *Instrucao
+	instLinha  int
+	instOffset int64
+	regLinha   int
+	regOffset  int64
+	regTexto   string // início do último registro lido, para relatórios
+}
+
+// NovoLeitorSQL cria um leitor sobre o conteúdo de r
+func NovoLeitorSQL(r io.Reader) *LeitorSQL {
+	return &LeitorSQL{lx: NovoLexer(bufio.NewReaderSize(r, 64*1024))}
+}
+
+// Proxima avança para a próxima instrução, descartando os registros ainda
+// não lidos do INSERT anterior. Retorna io.EOF ao final do dump. Problemas
+// de sintaxe vêm como *ErroParse e a instrução defeituosa é descartada, de
+// modo que a leitura pode continuar; outros erros são de E/S e encerram a
+// leitura.
+func (l *LeitorSQL) Proxima() (*Instrucao, error) {
+	for l.emInsert {
+		if _, err := l.ProximoRegistro(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	l.atual = nil
+	l.regTexto = ""
+
+	for {
+		tok, err := l.lx.Proximo()
+		if err != nil {
+			l.numero++
+			return nil, l.falhar(err)
+		}
+		if tok.Tipo == TokenFim {
+			return nil, io.EOF
+		}
+		if tok.Tipo == TokenSimbolo && tok.Texto == ";" {
+			continue // instrução vazia, como após /*!40101 ... */;
+		}
+
+		l.numero++
+		inst := &Instrucao{Numero: l.numero}
+		l.atual = inst
+		l.instLinha, l.instOffset = l.lx.Posicao()
+		l.instOffset -= int64(len(tok.Texto))
+
+		if tok.Tipo != TokenPalavra {
+			err = l.pularInstrucao()
+		} else {
+			switch strings.ToUpper(tok.Texto) {
+			case "INSERT", "REPLACE":
+				err = l.lerCabecalhoInsert(inst)
+			case "CREATE":
+				err = l.lerCreate(inst)
+			case "DELIMITER":
+				// rotinas e triggers usam outro delimitador; nada nelas
+				// interessa à conversão
+				err = l.pularBlocoDelimitado()
+			default:
+				err = l.pularInstrucao()
+			}
+		}
+		if err != nil {
+			return inst, l.falhar(err)
+		}
+		return inst, nil
+	}
+}
+
+// falhar trata um erro encontrado no meio de uma instrução. Erros de E/S
+// são devolvidos como estão; erros de sintaxe viram *ErroParse e o restante
+// da instrução é descartado para que a leitura possa seguir.
+func (l *LeitorSQL) falhar(err error) error {
+	if l.lx.errLeitura != nil {
+		return l.lx.errLeitura
+	}
+	erro := l.ErroParse(err)
+	l.emInsert = false
+	for {
+		err := l.pularInstrucao()
+		if err == nil {
+			return erro
+		}
+		if l.lx.errLeitura != nil {
+			return l.lx.errLeitura
+		}
+		// outro erro de sintaxe no trecho descartado: segue até o ';'
+	}
+}
+
+// ErroParse monta um *ErroParse na posição do registro atual ou, se nenhum
+// registro foi lido, da instrução atual
+func (l *LeitorSQL) ErroParse(err error) *ErroParse {
+	erro := &ErroParse{Instrucao: l.numero, Linha: l.instLinha, Offset: l.instOffset, Err: err}
+	if l.atual != nil {
+		erro.Tabela = l.atual.Tabela
+	}
+	if l.regTexto != "" {
+		erro.Linha, erro.Offset, erro.Registro = l.regLinha, l.regOffset, l.regTexto
+	}
+	return erro
+}
+
+// ProximoRegistro retorna os valores do próximo registro do INSERT
+// corrente, ou io.EOF quando o INSERT acaba. Um registro malformado gera
+// *ErroParse e encerra o INSERT, pois não há como achar o próximo registro
+// com segurança.
+func (l *LeitorSQL) ProximoRegistro() ([]Valor, error) {
+	if !l.emInsert {
+		return nil, io.EOF
+	}
+	for {
+		tok, err := l.lx.Proximo()
+		if err != nil {
+			return nil, l.falhar(err)
+		}
+		switch {
+		case tok.Tipo == TokenFim, tok.Tipo == TokenSimbolo && tok.Texto == ";":
+			l.emInsert = false
+			return nil, io.EOF
+		case tok.Tipo == TokenSimbolo && tok.Texto == ",":
+			continue
+		case tok.Tipo == TokenSimbolo && tok.Texto == "(":
+			l.regLinha, l.regOffset = l.lx.Posicao()
+			l.regOffset--
+			l.lx.iniciarCaptura()
+			valores, err := lerTupla(l.lx)
+			l.regTexto = "(" + l.lx.encerrarCaptura()
+			if err != nil {
+				return nil, l.falhar(err)
+			}
+			return valores, nil
+		case tok.Tipo == TokenPalavra:
+			// ON DUPLICATE KEY UPDATE e afins não interessam à conversão
+			l.emInsert = false
+			if err := l.pularInstrucao(); err != nil {
+				return nil, l.falhar(err)
+			}
+			return nil, io.EOF
+		default:
+			return nil, l.falhar(fmt.Errorf("token inesperado entre registros: %q", tok.Texto))
+		}
+	}
+}
+
+// lerCabecalhoInsert lê "[INTO] tabela (colunas) VALUES" e deixa o leitor
+// posicionado no primeiro registro
+func (l *LeitorSQL) lerCabecalhoInsert(inst *Instrucao) error {
+	// Modificadores como IGNORE e o INTO, que o MySQL dispensa
+	for {
+		tok, err := l.lx.Proximo()
+		if err != nil {
+			return err
+		}
+		if tok.Tipo != TokenPalavra || !modificadorInsert(tok.Texto) {
+			l.lx.devolver(tok)
+			break
+		}
+	}
+	tabela, err := lerNomeQualificado(l.lx)
+	if err != nil {
+		return err
+	}
+	inst.Tipo = InstrucaoInsert
+	inst.Tabela = tabela
+
+	tok, err := l.lx.Proximo()
+	if err != nil {
+		return err
+	}
+	if tok.Tipo == TokenSimbolo && tok.Texto == "(" {
+		for {
+			coluna, err := lerIdentificador(l.lx)
+			if err != nil {
+				return fmt.Errorf("lista de colunas inválida: %v", err)
+			}
+			inst.Colunas = append(inst.Colunas, coluna)
+
+			sep, err := l.lx.Proximo()
+			if err != nil {
+				return err
+			}
+			if sep.Tipo == TokenSimbolo && sep.Texto == ")" {
+				break
+			}
+			if sep.Tipo != TokenSimbolo || sep.Texto != "," {
+				return fmt.Errorf("lista de colunas inválida: encontrado %q", sep.Texto)
+			}
+		}
+		if tok, err = l.lx.Proximo(); err != nil {
+			return err
+		}
+	}
+
+	if tok.Tipo != TokenPalavra || (!strings.EqualFold(tok.Texto, "VALUES") && !strings.EqualFold(tok.Texto, "VALUE")) {
+		// INSERT ... SELECT ou INSERT ... SET: sem registros literais
+		inst.Tipo = InstrucaoOutra
+		l.lx.devolver(tok)
+		return l.pularInstrucao()
+	}
+	l.emInsert = true
+	return nil
+}
+
+// modificadorInsert informa se a palavra pode vir entre INSERT (ou REPLACE)
+// e o nome da tabela
+func modificadorInsert(palavra string) bool {
+	switch strings.ToUpper(palavra) {
+	case "INTO", "IGNORE", "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY":
+		return true
+	}
+	return false
+}
+
+// lerCreate trata CREATE [TEMPORARY] TABLE; outros CREATE são ignorados
+func (l *LeitorSQL) lerCreate(inst *Instrucao) error {
+	tok, err := l.lx.Proximo()
+	if err != nil {
+		return err
+	}
+	if tok.Tipo == TokenPalavra && strings.EqualFold(tok.Texto, "TEMPORARY") {
+		if tok, err = l.lx.Proximo(); err != nil {
+			return err
+		}
+	}
+	if tok.Tipo != TokenPalavra || !strings.EqualFold(tok.Texto, "TABLE") {
+		l.lx.devolver(tok)
+		return l.pularInstrucao()
+	}
+	l.lx.devolver(tok)
+
+	tabela, err := lerCreateTable(l.lx)
+	if err != nil {
+		return fmt.Errorf("erro ao interpretar CREATE TABLE: %v", err)
+	}
+	inst.Tipo = InstrucaoCreateTable
+	inst.Tabela = tabela.Nome
+	inst.Create = tabela
+	// opções da tabela: ENGINE=InnoDB DEFAULT CHARSET=...
+	return l.pularInstrucao()
+}
+
+// pularInstrucao consome tokens até o ';' que encerra a instrução
+func (l *LeitorSQL) pularInstrucao() error {
+	for {
+		tok, err := l.lx.Proximo()
+		if err != nil {
+			return err
+		}
+		if tok.Tipo == TokenFim || (tok.Tipo == TokenSimbolo && tok.Texto == ";") {
+			return nil
+		}
+	}
+}
+
+// pularBlocoDelimitado trata "DELIMITER x": descarta tudo até a linha que
+// restaura o delimitador para ';'
+func (l *LeitorSQL) pularBlocoDelimitado() error {
+	delimitador, err := l.lx.lerLinha()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(delimitador) == ";" {
+		return nil
+	}
+	for {
+		linha, err := l.lx.lerLinha()
+		campos := strings.Fields(linha)
+		if len(campos) == 2 && strings.EqualFold(campos[0], "DELIMITER") && campos[1] == ";" {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lerDump percorre as instruções do dump e chama fn para cada registro
+// inserido. As colunas de cada registro vêm da lista explícita do INSERT,
+// do CREATE TABLE anterior no dump ou, na falta de ambos, da ordem padrão
+// informada em padroes. Instruções e registros defeituosos, inclusive os
+// recusados por fn, são anotados em rel e ignorados; só falhas de leitura
+// interrompem o processo. O andamento é informado a acomp a cada
+// intervaloLeitura registros. Retorna o esquema que o dump declarou.
+func lerDump(r io.Reader, padroes map[string][]string, rel *RelatorioParse, acomp *Acompanhamento, fn func(tabela string, reg Registro) error) (*Esquema, error) {
+	leitor := NovoLeitorSQL(r)
+	esquema := NovoEsquema()
+	var registros int64
+
+	for {
+		inst, err := leitor.Proxima()
+		if err == io.EOF {
+			_, bytes := leitor.lx.Posicao()
+			acomp.AvisarLeitura(registros, bytes)
+			return esquema, nil
+		}
+		var erroParse *ErroParse
+		if errors.As(err, &erroParse) {
+			rel.registrarErro(erroParse)
+			continue
+		}
+		if err != nil {
+			return esquema, fmt.Errorf("erro ao ler o dump: %v", err)
+		}
+
+		switch inst.Tipo {
+		case InstrucaoCreateTable:
+			esquema.adicionar(inst.Create)
+			continue
+		case InstrucaoInsert:
+		default:
+			continue
+		}
+
+		tabela := strings.ToLower(inst.Tabela)
+		if len(inst.Colunas) > 0 {
+			esquema.registrarColunas(inst.Tabela, inst.Colunas)
+		}
+		definicao := esquema.Tabela(tabela)
+
+		var colunas []string
+		var indices map[string]int
+		for {
+			valores, err := leitor.ProximoRegistro()
+			if err == io.EOF {
+				break
+			}
+			if errors.As(err, &erroParse) {
+				rel.registrarErro(erroParse)
+				break
+			}
+			if err != nil {
+				return esquema, fmt.Errorf("erro ao ler o dump: %v", err)
+			}
+			if registros++; registros%intervaloLeitura == 0 {
+				_, bytes := leitor.lx.Posicao()
+				acomp.AvisarLeitura(registros, bytes)
+			}
+			if indices == nil {
+				colunas = inst.Colunas
+				if len(colunas) == 0 {
+					var doCreate []string
+					if definicao != nil {
+						doCreate = definicao.NomesColunas()
+					}
+					colunas = colunasPosicionais(doCreate, padroes[tabela], len(valores))
+				}
+				indices = novoMapaColunas(colunas)
+			}
+			// Registros curtos são aceitos (dumps de versões antigas dos
+			// painéis têm menos colunas): as que faltam assumem o DEFAULT ou
+			// NULL em Registro.Campo. Só sobra de valores é erro.
+			if len(colunas) > 0 && len(valores) > len(colunas) {
+				rel.registrarErro(leitor.ErroParse(fmt.Errorf("registro com %d valores, esperado no máximo %d", len(valores), len(colunas))))
+				continue
+			}
+			if err := fn(tabela, Registro{colunas: indices, valores: valores, tabela: definicao}); err != nil {
+				rel.registrarErro(leitor.ErroParse(err))
+			}
+		}
+	}
+}
+
+// leitorContexto interrompe a leitura com o erro de ctx quando ele é
+// cancelado. Como o dump é lido em blocos, o cancelamento é percebido no
+// máximo um bloco depois.
+type leitorContexto struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func leitorComContexto(ctx context.Context, r io.Reader) io.Reader {
+	return leitorContexto{ctx: ctx, r: r}
+}
+
+func (l leitorContexto) Read(p []byte) (int, error) {
+	if err := l.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return l.r.Read(p)
+}
+
+// colunasPosicionais escolhe os nomes para um INSERT sem lista de colunas:
+// o CREATE TABLE do dump quando casa com a quantidade de valores, senão a
+// ordem padrão do painel
+func colunasPosicionais(doCreate, padrao []string, quantidade int) []string {
+	if len(doCreate) > 0 && quantidade == len(doCreate) {
+		return doCreate
+	}
+	return padrao
+}
@@ -0,0 +1,186 @@
+package conversao
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// registroLido é um registro entregue por lerDump
+type registroLido struct {
+	tabela string
+	reg    Registro
+}
+
+// lerSQL passa o dump por lerDump e devolve os registros lidos, o esquema
+// declarado e o relatório
+func lerSQL(t *testing.T, sql string, padroes map[string][]string) ([]registroLido, *Esquema, *RelatorioParse) {
+	t.Helper()
+	var lidos []registroLido
+	rel := &RelatorioParse{}
+	esquema, err := lerDump(strings.NewReader(sql), padroes, rel, nil, func(tabela string, reg Registro) error {
+		lidos = append(lidos, registroLido{tabela, reg})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lidos, esquema, rel
+}
+
+// descrever mostra as colunas pedidas de cada registro, ex.: "t: a=1 b=NULL"
+func descrever(lidos []registroLido, colunas ...string) []string {
+	var linhas []string
+	for _, l := range lidos {
+		campos := []string{l.tabela + ":"}
+		for _, c := range colunas {
+			v := l.reg.Campo(c)
+			if v.Nulo {
+				campos = append(campos, c+"=NULL")
+			} else {
+				campos = append(campos, c+"="+v.Texto)
+			}
+		}
+		linhas = append(linhas, strings.Join(campos, " "))
+	}
+	return linhas
+}
+
+func TestLerDumpColunas(t *testing.T) {
+	padroes := map[string][]string{"t": {"a", "b"}, "u": {"a", "b"}}
+	casos := []struct {
+		nome     string
+		sql      string
+		esperado []string
+	}{
+		{"lista explícita", "INSERT INTO `t` (`b`, `a`) VALUES (1,2),(3,4);",
+			[]string{"t: a=2 b=1", "t: a=4 b=3"}},
+		{"lista parcial", "INSERT INTO t (b) VALUES (7);",
+			[]string{"t: a=NULL b=7"}},
+		{"sem lista usa o padrão", "INSERT INTO t VALUES (1,2);",
+			[]string{"t: a=1 b=2"}},
+		{"sem lista usa o CREATE TABLE", "CREATE TABLE t (b int, a int);\nINSERT INTO t VALUES (1,2);",
+			[]string{"t: a=2 b=1"}},
+		{"CREATE TABLE de outro tamanho cai no padrão", "CREATE TABLE t (x int);\nINSERT INTO t VALUES (1,2);",
+			[]string{"t: a=1 b=2"}},
+		{"INSERT sem INTO", "INSERT t VALUES (1,2);\nINSERT INTO u VALUES (3,4);",
+			[]string{"t: a=1 b=2", "u: a=3 b=4"}},
+		{"modificadores", "INSERT IGNORE INTO t (a) VALUES (5);\nINSERT LOW_PRIORITY u (b) VALUES (6);",
+			[]string{"t: a=5 b=NULL", "u: a=NULL b=6"}},
+		{"REPLACE", "REPLACE INTO t (a, b) VALUES (1,2);",
+			[]string{"t: a=1 b=2"}},
+		{"banco.tabela", "INSERT INTO `painel`.`t` (a) VALUES (1);",
+			[]string{"t: a=1 b=NULL"}},
+		{"nomes em maiúsculas", "INSERT INTO T (A, B) VALUES (1,2);",
+			[]string{"t: a=1 b=2"}},
+		{"INSERT ... SELECT é ignorado", "INSERT INTO t SELECT * FROM u;\nINSERT INTO t (a) VALUES (9);",
+			[]string{"t: a=9 b=NULL"}},
+		{"ON DUPLICATE KEY UPDATE", "INSERT INTO t (a, b) VALUES (1,2) ON DUPLICATE KEY UPDATE b=VALUES(b);\nINSERT INTO u VALUES (3,4);",
+			[]string{"t: a=1 b=2", "u: a=3 b=4"}},
+		{"outras instruções são ignoradas", "DROP TABLE IF EXISTS t;\nLOCK TABLES t WRITE;\nINSERT INTO t VALUES (1,2);\nUNLOCK TABLES;",
+			[]string{"t: a=1 b=2"}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			lidos, _, rel := lerSQL(t, c.sql, padroes)
+			if rel.TotalErros > 0 {
+				t.Errorf("erros inesperados: %v", rel.Erros)
+			}
+			if obtido := descrever(lidos, "a", "b"); fmt.Sprint(obtido) != fmt.Sprint(c.esperado) {
+				t.Errorf("registros:\n obtido   %q\n esperado %q", obtido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestLerDumpInsertGrandeNumaLinha(t *testing.T) {
+	// Um INSERT de vários MB numa linha só, com um valor de 3 MB que tem
+	// ';', parênteses e aspas escapadas no meio
+	const registros = 50000
+	grande := strings.Repeat(`a;b),(c\'`, (3<<20)/9)
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO `t` VALUES ")
+	for i := 0; i < registros; i++ {
+		fmt.Fprintf(&sb, "(%d,'valor de teste número %d'),", i, i)
+	}
+	fmt.Fprintf(&sb, "(%d,'%s');INSERT INTO `t` VALUES (-1,'fim');", registros, grande)
+	if sb.Len() < 4<<20 {
+		t.Fatalf("dump de teste com só %d bytes", sb.Len())
+	}
+
+	lidos, _, rel := lerSQL(t, sb.String(), map[string][]string{"t": {"a", "b"}})
+	if rel.TotalErros > 0 {
+		t.Fatalf("erros inesperados: %v", rel.Erros)
+	}
+	if len(lidos) != registros+2 {
+		t.Fatalf("%d registros lidos, esperado %d", len(lidos), registros+2)
+	}
+	if v := lidos[registros].reg.Campo("b").Texto; v != strings.ReplaceAll(grande, `\'`, "'") {
+		t.Errorf("valor grande com %d bytes, esperado %d", len(v), len(grande)-len(grande)/9)
+	}
+	if v := lidos[registros+1].reg.Campo("a").Texto; v != "-1" {
+		t.Errorf("último registro com a=%s, esperado -1", v)
+	}
+}
+
+func TestLerDumpRegistrosCurtosELongos(t *testing.T) {
+	sql := "CREATE TABLE t (a int, b varchar(10) DEFAULT 'x', c int);\n" +
+		// O primeiro registro define as colunas posicionais (as do CREATE)
+		"INSERT INTO t VALUES (1,'y',3),(2),(1,2,3,4),(4,'z',6);\n" +
+		"INSERT INTO t (a, b) VALUES (5),(6,'w',7),(8,'v');"
+
+	lidos, _, rel := lerSQL(t, sql, nil)
+	esperado := []string{
+		"t: a=1 b=y c=3",
+		"t: a=2 b=x c=NULL", // curto: o que falta assume o DEFAULT ou NULL
+		"t: a=4 b=z c=6",
+		"t: a=5 b=x c=NULL",
+		"t: a=8 b=v c=NULL",
+	}
+	if obtido := descrever(lidos, "a", "b", "c"); fmt.Sprint(obtido) != fmt.Sprint(esperado) {
+		t.Errorf("registros:\n obtido   %q\n esperado %q", obtido, esperado)
+	}
+	// Só os registros com valores a mais são recusados
+	if rel.TotalErros != 2 {
+		t.Fatalf("%d erros, esperado 2: %v", rel.TotalErros, rel.Erros)
+	}
+	for i, registro := range []string{"(1,2,3,4)", "(6,'w',7)"} {
+		if e := rel.Erros[i]; e.Registro != registro || !strings.Contains(e.Err.Error(), "valores") {
+			t.Errorf("erro %d = %v, esperado o registro %s", i, &e, registro)
+		}
+	}
+}
+
+func TestErroParsePosicao(t *testing.T) {
+	sql := "CREATE TABLE t (a int, b int);\n" + // linha 1, bytes 0 a 30
+		"INSERT INTO t VALUES (1,2),\n" + // linha 2, a partir do byte 31
+		"(3,4,5),\n" + // linha 3, byte 59
+		"(6,7);\n" +
+		"INSERT INTO t VALUES (8 9),(10,11);\n" + // linha 5, byte 75; registro no 96
+		"INSERT INTO 'x' VALUES (1);\n" + // linha 6, byte 111
+		"INSERT INTO t VALUES (12,13);"
+
+	lidos, _, rel := lerSQL(t, sql, nil)
+	if obtido := descrever(lidos, "a"); fmt.Sprint(obtido) != fmt.Sprint([]string{"t: a=1", "t: a=6", "t: a=12"}) {
+		t.Errorf("registros lidos: %q", obtido)
+	}
+
+	esperado := []ErroParse{
+		{Tabela: "t", Instrucao: 2, Linha: 3, Offset: 59, Registro: "(3,4,5)"},
+		{Tabela: "t", Instrucao: 3, Linha: 5, Offset: 96, Registro: "(8 9"},
+		{Instrucao: 4, Linha: 6, Offset: 111},
+	}
+	if len(rel.Erros) != len(esperado) {
+		t.Fatalf("%d erros, esperado %d: %v", len(rel.Erros), len(esperado), rel.Erros)
+	}
+	for i, e := range esperado {
+		obtido := rel.Erros[i]
+		if obtido.Tabela != e.Tabela || obtido.Instrucao != e.Instrucao || obtido.Linha != e.Linha ||
+			obtido.Offset != e.Offset || !strings.HasPrefix(obtido.Registro, e.Registro) {
+			t.Errorf("erro %d = %+v, esperado %+v", i, obtido, e)
+		}
+		if obtido.Err == nil {
+			t.Errorf("erro %d sem causa", i)
+		}
+	}
+}
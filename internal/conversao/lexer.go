@@ -0,0 +1,470 @@
+package conversao
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TipoToken identifica a categoria de um token SQL
+type TipoToken int
+
+const (
+	TokenFim           TipoToken = iota
+	TokenPalavra                 // palavra-chave ou identificador sem aspas (INSERT, NULL, nomes)
+	TokenIdentificador           // identificador entre crases
+	TokenString                  // literal entre aspas simples ou duplas, já sem escapes
+	TokenNumero                  // literal numérico
+	TokenHex                     // literal hexadecimal (0x41 ou X'41'), já decodificado
+	TokenBinario                 // literal binário (0b01 ou b'01'), já decodificado
+	TokenSimbolo                 // pontuação: ( ) , ; . = -
+)
+
+// Token representa um elemento léxico de um dump SQL
+type Token struct {
+	Tipo  TipoToken
+	Texto string
+}
+
+// Lexer quebra um dump SQL do MySQL em tokens, respeitando literais,
+// sequências de escape e comentários
+type Lexer struct {
+	r        *bufio.Reader
+	pendente *Token // token devolvido, entregue na próxima chamada
+
+	linha  int   // linha atual, a partir de 1
+	offset int64 // bytes consumidos desde o início da entrada
+
+	capturando bool
+	captura    []byte // trecho bruto lido durante a captura, limitado
+
+	// errLeitura guarda a última falha do leitor subjacente, para separar
+	// problemas de E/S de erros de sintaxe do dump
+	errLeitura error
+}
+
+// limiteCaptura limita o trecho guardado de um registro para relatórios
+const limiteCaptura = 200
+
+// NovoLexer cria um lexer sobre o conteúdo de r
+func NovoLexer(r io.Reader) *Lexer {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Lexer{r: br, linha: 1}
+}
+
+// Posicao retorna a linha e o deslocamento em bytes do próximo byte a ser
+// lido
+func (l *Lexer) Posicao() (int, int64) {
+	return l.linha, l.offset
+}
+
+// iniciarCaptura passa a guardar o texto bruto lido, até limiteCaptura bytes
+func (l *Lexer) iniciarCaptura() {
+	l.capturando = true
+	l.captura = l.captura[:0]
+}
+
+// encerrarCaptura para a captura e retorna o texto guardado
+func (l *Lexer) encerrarCaptura() string {
+	l.capturando = false
+	texto := string(l.captura)
+	if len(l.captura) >= limiteCaptura {
+		texto += "..."
+	}
+	return texto
+}
+
+// lerByte consome um byte, mantendo a posição e a captura em dia
+func (l *Lexer) lerByte() (byte, error) {
+	c, err := l.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			l.errLeitura = err
+		}
+		return c, err
+	}
+	l.offset++
+	if c == '\n' {
+		l.linha++
+	}
+	if l.capturando && len(l.captura) < limiteCaptura {
+		l.captura = append(l.captura, c)
+	}
+	return c, nil
+}
+
+// Proximo retorna o próximo token, ignorando espaços e comentários.
+// Ao final da entrada retorna um token do tipo TokenFim.
+func (l *Lexer) Proximo() (Token, error) {
+	if l.pendente != nil {
+		tok := *l.pendente
+		l.pendente = nil
+		return tok, nil
+	}
+	for {
+		c, err := l.lerByte()
+		if err == io.EOF {
+			return Token{Tipo: TokenFim}, nil
+		}
+		if err != nil {
+			return Token{}, err
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case c == '#':
+			if err := l.pularLinha(); err != nil {
+				return Token{}, err
+			}
+			continue
+		case c == '-':
+			// "-- " só é comentário quando seguido de espaço ou fim de linha
+			if prox, _ := l.r.Peek(2); len(prox) >= 1 && prox[0] == '-' &&
+				(len(prox) == 1 || prox[1] == ' ' || prox[1] == '\t' || prox[1] == '\n' || prox[1] == '\r') {
+				if err := l.pularLinha(); err != nil {
+					return Token{}, err
+				}
+				continue
+			}
+			return Token{Tipo: TokenSimbolo, Texto: "-"}, nil
+		case c == '/':
+			if l.espiar() == '*' {
+				l.lerByte()
+				if err := l.pularComentarioBloco(); err != nil {
+					return Token{}, err
+				}
+				continue
+			}
+			return Token{Tipo: TokenSimbolo, Texto: "/"}, nil
+		case c == '\'' || c == '"':
+			texto, err := l.lerString(c)
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Tipo: TokenString, Texto: texto}, nil
+		case c == '`':
+			texto, err := l.lerIdentificador()
+			if err != nil {
+				return Token{}, err
+			}
+			return Token{Tipo: TokenIdentificador, Texto: texto}, nil
+		case c >= '0' && c <= '9':
+			return l.lerNumero(c)
+		case c == '.' && isDigito(l.espiar()):
+			return l.lerNumero(c)
+		case isInicioPalavra(c):
+			return l.lerPalavra(c)
+		default:
+			return Token{Tipo: TokenSimbolo, Texto: string(c)}, nil
+		}
+	}
+}
+
+// devolver faz com que o token seja entregue de novo pela próxima chamada
+// a Proximo; só um token pode ser devolvido por vez
+func (l *Lexer) devolver(tok Token) {
+	l.pendente = &tok
+}
+
+// espiar devolve o próximo byte sem consumi-lo (0 no fim da entrada)
+func (l *Lexer) espiar() byte {
+	b, err := l.r.Peek(1)
+	if err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (l *Lexer) pularLinha() error {
+	for {
+		c, err := l.lerByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c == '\n' {
+			return nil
+		}
+	}
+}
+
+// lerLinha lê o restante da linha atual sem interpretá-la
+func (l *Lexer) lerLinha() (string, error) {
+	linha, err := l.r.ReadString('\n')
+	l.offset += int64(len(linha))
+	if strings.HasSuffix(linha, "\n") {
+		l.linha++
+	}
+	return strings.TrimRight(linha, "\r\n"), err
+}
+
+func (l *Lexer) pularComentarioBloco() error {
+	anterior := byte(0)
+	for {
+		c, err := l.lerByte()
+		if err == io.EOF {
+			return fmt.Errorf("comentário /* */ não terminado")
+		}
+		if err != nil {
+			return err
+		}
+		if anterior == '*' && c == '/' {
+			return nil
+		}
+		anterior = c
+	}
+}
+
+// lerString lê um literal delimitado por aspas, tratando escapes com barra
+// invertida e aspas duplicadas (” ou "")
+func (l *Lexer) lerString(aspa byte) (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := l.lerByte()
+		if err == io.EOF {
+			return "", fmt.Errorf("literal de texto não terminado")
+		}
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\\':
+			e, err := l.lerByte()
+			if err == io.EOF {
+				return "", fmt.Errorf("literal de texto não terminado")
+			}
+			if err != nil {
+				return "", err
+			}
+			switch e {
+			case '0':
+				sb.WriteByte(0)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'Z':
+				sb.WriteByte(0x1a)
+			case '%', '_':
+				// mantidos com a barra, como faz o MySQL
+				sb.WriteByte('\\')
+				sb.WriteByte(e)
+			default:
+				sb.WriteByte(e)
+			}
+		case aspa:
+			if l.espiar() == aspa {
+				l.lerByte()
+				sb.WriteByte(aspa)
+				continue
+			}
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func (l *Lexer) lerIdentificador() (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := l.lerByte()
+		if err == io.EOF {
+			return "", fmt.Errorf("identificador entre crases não terminado")
+		}
+		if err != nil {
+			return "", err
+		}
+		if c == '`' {
+			if l.espiar() == '`' {
+				l.lerByte()
+				sb.WriteByte('`')
+				continue
+			}
+			return sb.String(), nil
+		}
+		sb.WriteByte(c)
+	}
+}
+
+func (l *Lexer) lerNumero(primeiro byte) (Token, error) {
+	if primeiro == '0' {
+		switch l.espiar() {
+		case 'x', 'X':
+			l.lerByte()
+			digitos := l.lerEnquanto(isHex)
+			return literalHex(digitos)
+		case 'b', 'B':
+			l.lerByte()
+			digitos := l.lerEnquanto(func(c byte) bool { return c == '0' || c == '1' })
+			return literalBinario(digitos)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteByte(primeiro)
+	sb.WriteString(l.lerEnquanto(func(c byte) bool { return isDigito(c) || c == '.' }))
+	// expoente: 1e10, 1.5E-3
+	if c := l.espiar(); c == 'e' || c == 'E' {
+		l.lerByte()
+		sb.WriteByte(c)
+		if s := l.espiar(); s == '+' || s == '-' {
+			l.lerByte()
+			sb.WriteByte(s)
+		}
+		sb.WriteString(l.lerEnquanto(isDigito))
+	}
+	return Token{Tipo: TokenNumero, Texto: sb.String()}, nil
+}
+
+func (l *Lexer) lerPalavra(primeiro byte) (Token, error) {
+	// X'41' e B'01' são literais, não palavras
+	if (primeiro == 'x' || primeiro == 'X' || primeiro == 'b' || primeiro == 'B') && l.espiar() == '\'' {
+		l.lerByte()
+		digitos, err := l.lerString('\'')
+		if err != nil {
+			return Token{}, err
+		}
+		if primeiro == 'x' || primeiro == 'X' {
+			return literalHex(digitos)
+		}
+		return literalBinario(digitos)
+	}
+
+	var sb strings.Builder
+	sb.WriteByte(primeiro)
+	sb.WriteString(l.lerEnquanto(isParteDePalavra))
+	return Token{Tipo: TokenPalavra, Texto: sb.String()}, nil
+}
+
+func (l *Lexer) lerEnquanto(aceita func(byte) bool) string {
+	var sb strings.Builder
+	for {
+		prox, err := l.r.Peek(1)
+		if err != nil || !aceita(prox[0]) {
+			return sb.String()
+		}
+		c, _ := l.lerByte()
+		sb.WriteByte(c)
+	}
+}
+
+func literalHex(digitos string) (Token, error) {
+	if len(digitos)%2 == 1 {
+		digitos = "0" + digitos
+	}
+	b, err := hex.DecodeString(digitos)
+	if err != nil {
+		return Token{}, fmt.Errorf("literal hexadecimal inválido: %v", err)
+	}
+	return Token{Tipo: TokenHex, Texto: string(b)}, nil
+}
+
+func literalBinario(digitos string) (Token, error) {
+	// completa à esquerda até um múltiplo de 8 bits
+	for len(digitos)%8 != 0 {
+		digitos = "0" + digitos
+	}
+	b := make([]byte, 0, len(digitos)/8)
+	for i := 0; i < len(digitos); i += 8 {
+		var v byte
+		for _, d := range digitos[i : i+8] {
+			if d != '0' && d != '1' {
+				return Token{}, fmt.Errorf("literal binário inválido: %q", digitos)
+			}
+			v = v<<1 | byte(d-'0')
+		}
+		b = append(b, v)
+	}
+	return Token{Tipo: TokenBinario, Texto: string(b)}, nil
+}
+
+func isDigito(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigito(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isInicioPalavra(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$' || c >= 0x80
+}
+
+func isParteDePalavra(c byte) bool {
+	return isInicioPalavra(c) || isDigito(c)
+}
+
+// lerTupla lê os valores de um registro até o ')' correspondente; o '('
+// inicial já deve ter sido consumido
+func lerTupla(lx *Lexer) ([]Valor, error) {
+	var campos []Valor
+	for {
+		valor, fim, err := lerValor(lx)
+		if err != nil {
+			return campos, err
+		}
+		campos = append(campos, valor)
+
+		tok, err := lx.Proximo()
+		if err != nil {
+			return campos, err
+		}
+		if tok.Tipo == TokenSimbolo && tok.Texto == ")" {
+			return campos, nil
+		}
+		if fim || tok.Tipo != TokenSimbolo || tok.Texto != "," {
+			lx.devolver(tok)
+			return campos, fmt.Errorf("esperado ',' ou ')' após valor, encontrado %q", tok.Texto)
+		}
+	}
+}
+
+// lerValor lê um único valor literal de um registro. O retorno fim indica
+// que a entrada acabou antes do valor.
+func lerValor(lx *Lexer) (Valor, bool, error) {
+	tok, err := lx.Proximo()
+	if err != nil {
+		return Valor{}, false, err
+	}
+	switch tok.Tipo {
+	case TokenFim:
+		return Valor{}, true, fmt.Errorf("fim inesperado dentro de um registro")
+	case TokenString, TokenNumero, TokenHex, TokenBinario:
+		return Valor{Texto: tok.Texto}, false, nil
+	case TokenPalavra:
+		if strings.EqualFold(tok.Texto, "NULL") {
+			return Valor{Nulo: true}, false, nil
+		}
+		// introdutor de charset: _utf8mb4'texto', _binary 'dados'
+		if strings.HasPrefix(tok.Texto, "_") {
+			return lerValor(lx)
+		}
+		return Valor{Texto: tok.Texto}, false, nil
+	case TokenSimbolo:
+		if tok.Texto == "-" || tok.Texto == "+" {
+			num, fim, err := lerValor(lx)
+			if err != nil {
+				return Valor{}, fim, err
+			}
+			if tok.Texto == "-" {
+				num.Texto = "-" + num.Texto
+			}
+			return num, false, nil
+		}
+	}
+	lx.devolver(tok)
+	return Valor{}, false, fmt.Errorf("valor inesperado em registro: %q", tok.Texto)
+}
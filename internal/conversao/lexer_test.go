@@ -0,0 +1,135 @@
+package conversao
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tokens lê todos os tokens da entrada, até TokenFim
+func tokens(t *testing.T, entrada string) []Token {
+	t.Helper()
+	lx := NovoLexer(strings.NewReader(entrada))
+	var lidos []Token
+	for {
+		tok, err := lx.Proximo()
+		if err != nil {
+			t.Fatalf("Proximo(%q): %v", entrada, err)
+		}
+		if tok.Tipo == TokenFim {
+			return lidos
+		}
+		lidos = append(lidos, tok)
+	}
+}
+
+func TestLexerTokens(t *testing.T) {
+	str := func(s string) Token { return Token{Tipo: TokenString, Texto: s} }
+	sim := func(s string) Token { return Token{Tipo: TokenSimbolo, Texto: s} }
+	pal := func(s string) Token { return Token{Tipo: TokenPalavra, Texto: s} }
+	num := func(s string) Token { return Token{Tipo: TokenNumero, Texto: s} }
+
+	casos := []struct {
+		nome     string
+		entrada  string
+		esperado []Token
+	}{
+		{"aspa com barra", `'O\'Brien'`, []Token{str("O'Brien")}},
+		{"aspa duplicada", `'O''Brien'`, []Token{str("O'Brien")}},
+		{"string vazia", `''`, []Token{str("")}},
+		{"aspas duplas duplicadas", `"diz ""oi"""`, []Token{str(`diz "oi"`)}},
+		{"aspa simples dentro de aspas duplas", `"it's"`, []Token{str("it's")}},
+		{"ponto e vírgula na string", `'a;b'; 1`, []Token{str("a;b"), sim(";"), num("1")}},
+		{"parênteses e vírgula na string", `('a,(b)')`, []Token{sim("("), str("a,(b)"), sim(")")}},
+		{"escapes", `'\n\t\r\0\Z\\\"x'`, []Token{str("\n\t\r\x00\x1a\\\"x")}},
+		{"curingas mantêm a barra", `'\%\_'`, []Token{str(`\%\_`)}},
+		{"barra antes de letra comum", `'\q'`, []Token{str("q")}},
+		{"identificador com crases", "`nome da tabela`", []Token{{Tipo: TokenIdentificador, Texto: "nome da tabela"}}},
+		{"crase duplicada", "`a``b`", []Token{{Tipo: TokenIdentificador, Texto: "a`b"}}},
+		{"hex 0x", `0x4142`, []Token{{Tipo: TokenHex, Texto: "AB"}}},
+		{"hex 0x ímpar", `0x141`, []Token{{Tipo: TokenHex, Texto: "\x01\x41"}}},
+		{"hex X''", `X'4142'`, []Token{{Tipo: TokenHex, Texto: "AB"}}},
+		{"hex x'' minúsculo", `x'41'`, []Token{{Tipo: TokenHex, Texto: "A"}}},
+		{"binário b''", `b'01000001'`, []Token{{Tipo: TokenBinario, Texto: "A"}}},
+		{"binário 0b", `0b1`, []Token{{Tipo: TokenBinario, Texto: "\x01"}}},
+		{"binário curto completado", `B'101'`, []Token{{Tipo: TokenBinario, Texto: "\x05"}}},
+		{"números", `1 2.5 .5 1e10 1.5E-3`, []Token{num("1"), num("2.5"), num(".5"), num("1e10"), num("1.5E-3")}},
+		{"comentário --", "1 -- comentário; 'x'\n2", []Token{num("1"), num("2")}},
+		{"-- sem espaço não é comentário", "1--2", []Token{num("1"), sim("-"), sim("-"), num("2")}},
+		{"-- no fim da entrada", "1 --", []Token{num("1")}},
+		{"comentário /* */", "1 /* a; 'b' */ 2", []Token{num("1"), num("2")}},
+		{"comentário /* */ com asteriscos", "1 /** x **/ 2", []Token{num("1"), num("2")}},
+		{"comentário #", "1 # 'não fechada\n2", []Token{num("1"), num("2")}},
+		{"comentário dentro da string não conta", `'a -- b /* c */ # d'`, []Token{str("a -- b /* c */ # d")}},
+		{"palavras", `INSERT INTO _utf8mb4 NULL`, []Token{pal("INSERT"), pal("INTO"), pal("_utf8mb4"), pal("NULL")}},
+		{"utf-8 na string", `'ação'`, []Token{str("ação")}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			obtido := tokens(t, c.entrada)
+			if fmt.Sprintf("%q", obtido) != fmt.Sprintf("%q", c.esperado) {
+				t.Errorf("tokens de %q:\n obtido   %q\n esperado %q", c.entrada, obtido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestLexerErros(t *testing.T) {
+	casos := []struct {
+		nome    string
+		entrada string
+	}{
+		{"string não terminada", `'abc`},
+		{"string terminada em barra", `'abc\`},
+		{"aspa escapada no fim", `'O\'`},
+		{"identificador não terminado", "`abc"},
+		{"comentário de bloco não terminado", "1 /* abc"},
+		{"hex inválido", `X'4G'`},
+		{"binário inválido", `b'012'`},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			lx := NovoLexer(strings.NewReader(c.entrada))
+			for {
+				tok, err := lx.Proximo()
+				if err != nil {
+					return
+				}
+				if tok.Tipo == TokenFim {
+					t.Fatalf("%q deveria dar erro", c.entrada)
+				}
+			}
+		})
+	}
+}
+
+func TestLexerPosicao(t *testing.T) {
+	lx := NovoLexer(strings.NewReader("1\n'a\nb'\n2"))
+	for _, esperado := range []struct {
+		texto string
+		linha int
+	}{{"1", 1}, {"a\nb", 3}, {"2", 4}} {
+		tok, err := lx.Proximo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linha, _ := lx.Posicao(); tok.Texto != esperado.texto || linha != esperado.linha {
+			t.Errorf("token %q na linha %d, esperado %q na linha %d", tok.Texto, linha, esperado.texto, esperado.linha)
+		}
+	}
+}
+
+func TestLerTupla(t *testing.T) {
+	lx := NovoLexer(strings.NewReader(`(1, -2, 'O\'Brien', NULL, _utf8mb4'x', 0x41, 'a;b')`))
+	if tok, err := lx.Proximo(); err != nil || tok.Texto != "(" {
+		t.Fatalf("esperado '(', obtido %q (%v)", tok.Texto, err)
+	}
+	valores, err := lerTupla(lx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperado := []Valor{{Texto: "1"}, {Texto: "-2"}, {Texto: "O'Brien"}, {Nulo: true}, {Texto: "x"}, {Texto: "A"}, {Texto: "a;b"}}
+	if fmt.Sprintf("%+v", valores) != fmt.Sprintf("%+v", esperado) {
+		t.Errorf("valores = %+v, esperado %+v", valores, esperado)
+	}
+}
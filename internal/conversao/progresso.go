@@ -0,0 +1,27 @@
+package conversao
+
+// intervaloLeitura é de quantos em quantos registros a leitura é informada
+const intervaloLeitura = 1000
+
+// Acompanhamento recebe o andamento das etapas longas de uma conversão. Um
+// *Acompanhamento nulo, ou um campo nulo, desliga o aviso correspondente.
+type Acompanhamento struct {
+	// Leitura recebe os registros lidos e os bytes do dump já consumidos
+	Leitura func(registros, bytes int64)
+	// Carga recebe as linhas já gravadas no MySQL e o total a gravar
+	Carga func(linhas, total int64)
+}
+
+// AvisarLeitura repassa o andamento da leitura do dump
+func (a *Acompanhamento) AvisarLeitura(registros, bytes int64) {
+	if a != nil && a.Leitura != nil {
+		a.Leitura(registros, bytes)
+	}
+}
+
+// AvisarCarga repassa o andamento da carga no MySQL
+func (a *Acompanhamento) AvisarCarga(linhas, total int64) {
+	if a != nil && a.Carga != nil {
+		a.Carga(linhas, total)
+	}
+}
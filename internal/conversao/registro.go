@@ -0,0 +1,117 @@
+package conversao
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Dados é o resultado de uma etapa da conversão. O tipo concreto depende do
+// conversor (por exemplo *Database na leitura e *DatabaseExport depois da
+// transformação do Eclipse).
+type Dados interface {
+	// Diagnostico retorna o relatório de problemas encontrados no dump
+	Diagnostico() *RelatorioParse
+}
+
+// Converter converte dumps de um painel de origem para o banco de um painel
+// de destino, em três etapas: leitura, transformação e carga.
+type Converter interface {
+	// Origem identifica o formato do dump recebido, ex.: "eclipse"
+	Origem() string
+	// Destino identifica o painel cujo banco recebe os dados, ex.: "atlas"
+	Destino() string
+	// Nome é o texto exibido ao usuário
+	Nome() string
+	// Tabelas lista as tabelas esperadas no dump de origem e suas colunas
+	Tabelas() map[string][]string
+	// Ler interpreta o dump sem alterar os dados
+	Ler(ctx context.Context, entrada Entrada, acomp *Acompanhamento) (Dados, error)
+	// Transformar aplica as regras do painel de destino
+	Transformar(dados Dados) (Dados, error)
+	// Carregar envia os dados transformados para o MySQL
+	Carregar(ctx context.Context, dados Dados, dsn string, acomp *Acompanhamento) error
+	// Simular resume, a partir dos dados lidos, o que a carga faria
+	Simular(lidos Dados) *Simulacao
+}
+
+// Carregador envia os dados transformados de um conversor para o MySQL. Os
+// carregadores ficam no pacote db, que os registra com DefinirCarregador.
+type Carregador func(ctx context.Context, dados Dados, dsn string, acomp *Acompanhamento) error
+
+var (
+	conversores  []Converter
+	carregadores = make(map[string]Carregador)
+	registroMu   sync.RWMutex
+)
+
+// Chave identifica um conversor pelo par origem/destino
+func Chave(origem, destino string) string {
+	return origem + ">" + destino
+}
+
+// ChaveDe retorna a chave do conversor
+func ChaveDe(c Converter) string {
+	return Chave(c.Origem(), c.Destino())
+}
+
+// Registrar adiciona um conversor ao registro. Registrar duas vezes o mesmo
+// par origem/destino é erro de programação e causa panic.
+func Registrar(c Converter) {
+	registroMu.Lock()
+	defer registroMu.Unlock()
+
+	for _, existente := range conversores {
+		if ChaveDe(existente) == ChaveDe(c) {
+			panic("conversao: conversor registrado duas vezes: " + ChaveDe(c))
+		}
+	}
+	conversores = append(conversores, c)
+}
+
+// Obter retorna o conversor registrado para o par origem/destino
+func Obter(origem, destino string) (Converter, bool) {
+	return ObterPorChave(Chave(origem, destino))
+}
+
+// ObterPorChave retorna o conversor registrado com a chave informada
+func ObterPorChave(chave string) (Converter, bool) {
+	registroMu.RLock()
+	defer registroMu.RUnlock()
+
+	for _, c := range conversores {
+		if ChaveDe(c) == chave {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Conversores lista os conversores na ordem em que foram registrados
+func Conversores() []Converter {
+	registroMu.RLock()
+	defer registroMu.RUnlock()
+
+	lista := make([]Converter, len(conversores))
+	copy(lista, conversores)
+	return lista
+}
+
+// DefinirCarregador associa o carregador MySQL ao par origem/destino
+func DefinirCarregador(origem, destino string, fn Carregador) {
+	registroMu.Lock()
+	defer registroMu.Unlock()
+	carregadores[Chave(origem, destino)] = fn
+}
+
+// carregar executa o carregador registrado para o conversor
+func carregar(ctx context.Context, c Converter, dados Dados, dsn string, acomp *Acompanhamento) error {
+	registroMu.RLock()
+	fn := carregadores[ChaveDe(c)]
+	registroMu.RUnlock()
+
+	if fn == nil {
+		return fmt.Errorf("nenhum carregador registrado para %s", ChaveDe(c))
+	}
+	return fn(ctx, dados, dsn, acomp)
+}
@@ -0,0 +1,87 @@
+package conversao
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxErrosGuardados limita quantos erros o relatório mantém em detalhe
+const maxErrosGuardados = 100
+
+// ErroParse descreve um problema encontrado em um ponto do dump
+type ErroParse struct {
+	Tabela    string
+	Instrucao int    // número da instrução no dump, a partir de 1
+	Linha     int    // linha onde o registro ou instrução começa
+	Offset    int64  // deslocamento em bytes do mesmo ponto
+	Registro  string // trecho do registro problemático, quando houver
+	Err       error
+}
+
+func (e *ErroParse) Error() string {
+	var sb strings.Builder
+	if e.Tabela != "" {
+		fmt.Fprintf(&sb, "tabela %s, ", e.Tabela)
+	}
+	fmt.Fprintf(&sb, "instrução %d, linha %d (byte %d): %v", e.Instrucao, e.Linha, e.Offset, e.Err)
+	if e.Registro != "" {
+		fmt.Fprintf(&sb, " em %s", e.Registro)
+	}
+	return sb.String()
+}
+
+func (e *ErroParse) Unwrap() error {
+	return e.Err
+}
+
+// RelatorioParse reúne o que a leitura do dump encontrou além dos dados
+// convertidos
+type RelatorioParse struct {
+	// ColunasDesconhecidas lista, por tabela, as colunas do dump que o
+	// conversor não conhece e que portanto foram ignoradas
+	ColunasDesconhecidas map[string][]string
+	// Erros guarda os primeiros registros ou instruções descartados
+	Erros []ErroParse
+	// TotalErros conta todos os descartes, inclusive os não guardados
+	TotalErros int
+}
+
+// registrarErro conta o erro e o guarda enquanto houver espaço
+func (r *RelatorioParse) registrarErro(e *ErroParse) {
+	r.TotalErros++
+	if len(r.Erros) < maxErrosGuardados {
+		r.Erros = append(r.Erros, *e)
+	}
+}
+
+// Resumo descreve o relatório em texto para o usuário, ou "" se não houver
+// nada a relatar
+func (r *RelatorioParse) Resumo() string {
+	var sb strings.Builder
+
+	if r.TotalErros > 0 {
+		fmt.Fprintf(&sb, "%d registro(s) ou instrução(ões) com problema foram ignorados:\n", r.TotalErros)
+		for i, e := range r.Erros {
+			if i == 10 {
+				fmt.Fprintf(&sb, "... e mais %d\n", r.TotalErros-10)
+				break
+			}
+			fmt.Fprintf(&sb, "- %s\n", e.Error())
+		}
+	}
+
+	if len(r.ColunasDesconhecidas) > 0 {
+		tabelas := make([]string, 0, len(r.ColunasDesconhecidas))
+		for t := range r.ColunasDesconhecidas {
+			tabelas = append(tabelas, t)
+		}
+		sort.Strings(tabelas)
+
+		sb.WriteString("Colunas ignoradas (não reconhecidas pelo conversor):\n")
+		for _, t := range tabelas {
+			fmt.Fprintf(&sb, "- %s: %s\n", t, strings.Join(r.ColunasDesconhecidas[t], ", "))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,204 @@
+package conversao
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxExemplosSimulacao limita quantos itens de cada lista aparecem no texto
+const maxExemplosSimulacao = 10
+
+// ContagemTabela é a quantidade de registros lidos de uma tabela
+type ContagemTabela struct {
+	Tabela    string
+	Registros int
+}
+
+// Simulacao resume o que uma conversão faria no MySQL, sem executá-la
+type Simulacao struct {
+	Conversor          string
+	Tabelas            []ContagemTabela
+	ContatosGerados    int
+	EmailsGerados      int
+	Orfaos             []string // registros cujo dono não existe no dump
+	CategoriasAusentes []int    // categorias referenciadas e não declaradas
+	DatasInvalidas     []string // registros com data vazia ou inválida
+	Relatorio          *RelatorioParse
+}
+
+// Simular lê e transforma o dump com o conversor, sem carregar nada no
+// MySQL, e resume o resultado
+func Simular(ctx context.Context, conv Converter, entrada Entrada) (*Simulacao, error) {
+	lidos, err := conv.Ler(ctx, entrada, nil)
+	if err != nil {
+		return nil, err
+	}
+	sim := conv.Simular(lidos)
+	transformados, err := conv.Transformar(lidos)
+	if err != nil {
+		return nil, err
+	}
+	sim.Conversor = conv.Nome()
+	sim.Relatorio = transformados.Diagnostico()
+	return sim, nil
+}
+
+// Texto descreve a simulação para o usuário
+func (s *Simulacao) Texto() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Simulação da conversão (%s). Nada foi alterado no MySQL.\n\n", s.Conversor)
+	sb.WriteString("Registros por tabela:\n")
+	for _, t := range s.Tabelas {
+		fmt.Fprintf(&sb, "- %s: %d\n", t.Tabela, t.Registros)
+	}
+	fmt.Fprintf(&sb, "\nContatos gerados: %d\n", s.ContatosGerados)
+	fmt.Fprintf(&sb, "E-mails gerados: %d\n", s.EmailsGerados)
+
+	escreverLista(&sb, "Registros sem dono (desconhecido)", s.Orfaos)
+	categorias := make([]string, len(s.CategoriasAusentes))
+	for i, id := range s.CategoriasAusentes {
+		categorias[i] = fmt.Sprintf("%d", id)
+	}
+	escreverLista(&sb, "Categorias ausentes", categorias)
+	escreverLista(&sb, "Datas inválidas", s.DatasInvalidas)
+
+	if s.Relatorio != nil {
+		if resumo := s.Relatorio.Resumo(); resumo != "" {
+			sb.WriteString("\n")
+			sb.WriteString(resumo)
+		}
+	}
+	return sb.String()
+}
+
+func escreverLista(sb *strings.Builder, titulo string, itens []string) {
+	fmt.Fprintf(sb, "%s: %d\n", titulo, len(itens))
+	for i, item := range itens {
+		if i == maxExemplosSimulacao {
+			fmt.Fprintf(sb, "  ... e mais %d\n", len(itens)-maxExemplosSimulacao)
+			break
+		}
+		fmt.Fprintf(sb, "  - %s\n", item)
+	}
+}
+
+// categoriasAusentes ordena os ids referenciados que não estão em conhecidas
+func categoriasAusentes(referenciadas []int, conhecidas map[int]bool) []int {
+	vistas := make(map[int]bool)
+	var ausentes []int
+	for _, id := range referenciadas {
+		if !conhecidas[id] && !vistas[id] {
+			vistas[id] = true
+			ausentes = append(ausentes, id)
+		}
+	}
+	sort.Ints(ausentes)
+	return ausentes
+}
+
+// dataValida informa se o texto é uma data nos formatos aceitos pelo Eclipse
+func dataValida(data string) bool {
+	if _, err := time.Parse("2006-01-02 15:04:05", data); err == nil {
+		return true
+	}
+	_, err := time.Parse("2006-01-02", data)
+	return err == nil
+}
+
+// simularEclipse calcula a simulação a partir do dump do Eclipse, com as
+// mesmas regras de TransformarDatabase
+func simularEclipse(db *Database) *Simulacao {
+	sim := &Simulacao{
+		Tabelas: []ContagemTabela{
+			{"categorias", len(db.Categorias)},
+			{"usuarios", len(db.Usuarios)},
+			{"revenda", len(db.Revendas)},
+		},
+		EmailsGerados: len(db.Revendas),
+	}
+
+	conhecidas := make(map[int]bool)
+	for _, c := range db.Categorias {
+		conhecidas[c.SubID] = true
+	}
+	var referenciadas []int
+
+	for _, user := range db.Usuarios {
+		if Texto(user.Msg) == "" && len(user.Login) > 0 {
+			sim.ContatosGerados++
+		}
+		if getDonoUsuario(user, *db) == "desconhecido" {
+			sim.Orfaos = append(sim.Orfaos, "usuário "+user.Login)
+		}
+		if !dataValida(user.Validade) {
+			sim.DatasInvalidas = append(sim.DatasInvalidas, fmt.Sprintf("usuário %s: %q", user.Login, user.Validade))
+		}
+		referenciadas = append(referenciadas, user.SubID)
+	}
+	for _, rev := range db.Revendas {
+		if Texto(rev.Numero) == "" {
+			sim.ContatosGerados++
+		}
+		if getDonoRevenda(rev, *db) == "desconhecido" {
+			sim.Orfaos = append(sim.Orfaos, "revenda "+rev.Login)
+		}
+		if !dataValida(rev.Data) {
+			sim.DatasInvalidas = append(sim.DatasInvalidas, fmt.Sprintf("revenda %s: %q", rev.Login, rev.Data))
+		}
+		referenciadas = append(referenciadas, rev.Categoria)
+	}
+
+	sim.CategoriasAusentes = categoriasAusentes(referenciadas, conhecidas)
+	return sim
+}
+
+// simularFinal calcula a simulação a partir de um dump no formato final
+func simularFinal(db *DatabaseFinal) *Simulacao {
+	sim := &Simulacao{
+		Tabelas: []ContagemTabela{
+			{"accounts", len(db.Accounts)},
+			{"ssh_accounts", len(db.SSHAccounts)},
+			{"atribuidos", len(db.Atribuidos)},
+			{"categorias", len(db.Categorias)},
+		},
+		EmailsGerados: len(db.Accounts),
+		// o contato dos usuários SSH é sempre o número de exemplo
+		ContatosGerados: len(db.SSHAccounts),
+	}
+
+	contas := make(map[int]bool)
+	for _, acc := range db.Accounts {
+		contas[acc.ID] = true
+		if acc.Contato == contatoExemplo {
+			sim.ContatosGerados++
+		}
+	}
+	conhecidas := make(map[int]bool)
+	for _, c := range db.Categorias {
+		conhecidas[c.SubID] = true
+	}
+	var referenciadas []int
+
+	for _, ssh := range db.SSHAccounts {
+		if !contas[ssh.ByID] {
+			sim.Orfaos = append(sim.Orfaos, "usuário "+ssh.Login)
+		}
+		if ssh.Expira == dataInvalida {
+			sim.DatasInvalidas = append(sim.DatasInvalidas, "usuário "+ssh.Login)
+		}
+		referenciadas = append(referenciadas, ssh.CategoriaID)
+	}
+	for _, atr := range db.Atribuidos {
+		if !contas[atr.UserID] {
+			sim.Orfaos = append(sim.Orfaos, fmt.Sprintf("atribuído %d (conta %d)", atr.ID, atr.UserID))
+		}
+		referenciadas = append(referenciadas, atr.CategoriaID)
+	}
+
+	sim.CategoriasAusentes = categoriasAusentes(referenciadas, conhecidas)
+	return sim
+}
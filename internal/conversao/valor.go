@@ -0,0 +1,52 @@
+package conversao
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Valor é um literal lido de um INSERT. Nulo distingue o NULL do SQL de um
+// texto que por acaso contenha "NULL".
+type Valor struct {
+	Texto string
+	Nulo  bool
+}
+
+// String retorna o texto sem espaços nas pontas, ou "" quando o valor é NULL
+func (v Valor) String() string {
+	if v.Nulo {
+		return ""
+	}
+	return strings.TrimSpace(v.Texto)
+}
+
+// Ptr retorna o texto como ponteiro, ou nil quando o valor é NULL
+func (v Valor) Ptr() *string {
+	if v.Nulo {
+		return nil
+	}
+	s := strings.TrimSpace(v.Texto)
+	return &s
+}
+
+// Int interpreta o valor como inteiro (0 para NULL ou texto inválido)
+func (v Valor) Int() int {
+	var n int
+	fmt.Sscanf(v.String(), "%d", &n)
+	return n
+}
+
+// Float interpreta o valor como decimal (0 para NULL ou texto inválido)
+func (v Valor) Float() float64 {
+	var f float64
+	fmt.Sscanf(v.String(), "%f", &f)
+	return f
+}
+
+// Texto retorna o conteúdo de um campo anulável, ou "" quando é nil
+func Texto(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"conversao-db/internal/conversao"
+)
+
+const (
+	// intervaloCarga é de quantas em quantas linhas a carga é informada
+	intervaloCarga = 1000
+	// maxPlaceholders é o limite de parâmetros de uma instrução preparada
+	maxPlaceholders = 65535
+	// maxLinhasLote limita as linhas de cada INSERT mesmo com pacote grande
+	maxLinhasLote = 1000
+	// custoFixoValor estima os bytes que cada parâmetro ocupa além do dado
+	custoFixoValor = 9
+)
+
+// loteInsert agrupa linhas em INSERTs de várias linhas. Cada instrução fica
+// abaixo do limite de bytes informado (derivado do max_allowed_packet) e as
+// instruções preparadas são reaproveitadas entre lotes do mesmo tamanho.
+type loteInsert struct {
+	ctx       context.Context
+	tx        *sql.Tx
+	tabela    string
+	colunas   []string
+	limite    int
+	maxLinhas int
+
+	valores []interface{}
+	linhas  int
+	tamanho int
+	stmts   map[int]*sql.Stmt
+}
+
+// novoLoteInsert cria um lote para a tabela e colunas informadas
+func novoLoteInsert(ctx context.Context, tx *sql.Tx, limite int, tabela string, colunas ...string) *loteInsert {
+	maxLinhas := maxPlaceholders / len(colunas)
+	if maxLinhas > maxLinhasLote {
+		maxLinhas = maxLinhasLote
+	}
+	return &loteInsert{
+		ctx:       ctx,
+		tx:        tx,
+		tabela:    tabela,
+		colunas:   colunas,
+		limite:    limite,
+		maxLinhas: maxLinhas,
+		stmts:     make(map[int]*sql.Stmt),
+	}
+}
+
+// inserirTabelas grava as linhas de cada tabela em lotes, informando a
+// acomp as linhas já gravadas. Para assim que ctx for cancelado.
+func inserirTabelas(ctx context.Context, tx *sql.Tx, tabelas []conversao.TabelaDestino, acomp *conversao.Acompanhamento) error {
+	limite, err := limiteLote(tx)
+	if err != nil {
+		return err
+	}
+
+	var total, gravadas int64
+	for _, tabela := range tabelas {
+		total += int64(len(tabela.Linhas))
+	}
+	acomp.AvisarCarga(0, total)
+
+	for _, tabela := range tabelas {
+		lote := novoLoteInsert(ctx, tx, limite, tabela.Nome, tabela.Colunas...)
+		for _, linha := range tabela.Linhas {
+			if err := lote.Adicionar(linha...); err != nil {
+				return err
+			}
+			if gravadas++; gravadas%intervaloCarga == 0 {
+				acomp.AvisarCarga(gravadas, total)
+			}
+		}
+		if err := lote.Concluir(); err != nil {
+			return err
+		}
+	}
+	acomp.AvisarCarga(total, total)
+	return nil
+}
+
+// limiteLote consulta o max_allowed_packet do servidor e devolve quantos
+// bytes cada lote pode ocupar, com folga para o cabeçalho do pacote
+func limiteLote(tx *sql.Tx) (int, error) {
+	var pacote int
+	if err := tx.QueryRow("SELECT @@max_allowed_packet").Scan(&pacote); err != nil {
+		return 0, fmt.Errorf("erro ao consultar max_allowed_packet: %v", err)
+	}
+	return pacote / 4 * 3, nil
+}
+
+// Adicionar inclui uma linha no lote, enviando o lote atual antes se a
+// linha não couber nele
+func (l *loteInsert) Adicionar(valores ...interface{}) error {
+	if len(valores) != len(l.colunas) {
+		return fmt.Errorf("lote %s: %d valores para %d colunas", l.tabela, len(valores), len(l.colunas))
+	}
+	tamanho := tamanhoLinha(valores)
+	if l.linhas > 0 && (l.linhas == l.maxLinhas || l.tamanho+tamanho > l.limite) {
+		if err := l.enviar(); err != nil {
+			return err
+		}
+	}
+	l.valores = append(l.valores, valores...)
+	l.linhas++
+	l.tamanho += tamanho
+	return nil
+}
+
+// Concluir envia as linhas pendentes e libera as instruções preparadas
+func (l *loteInsert) Concluir() error {
+	err := l.enviar()
+	for _, stmt := range l.stmts {
+		stmt.Close()
+	}
+	l.stmts = nil
+	return err
+}
+
+// enviar executa o INSERT com as linhas acumuladas
+func (l *loteInsert) enviar() error {
+	if l.linhas == 0 {
+		return nil
+	}
+	stmt, ok := l.stmts[l.linhas]
+	if !ok {
+		var err error
+		stmt, err = l.tx.PrepareContext(l.ctx, l.sql(l.linhas))
+		if err != nil {
+			return fmt.Errorf("erro ao preparar insert em %s: %v", l.tabela, err)
+		}
+		l.stmts[l.linhas] = stmt
+	}
+	if _, err := stmt.ExecContext(l.ctx, l.valores...); err != nil {
+		return fmt.Errorf("erro ao inserir %d linha(s) em %s: %v", l.linhas, l.tabela, err)
+	}
+	l.valores = l.valores[:0]
+	l.linhas = 0
+	l.tamanho = 0
+	return nil
+}
+
+// sql monta "INSERT INTO tabela (colunas) VALUES (?, ...), (?, ...)"
+func (l *loteInsert) sql(linhas int) string {
+	linha := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(l.colunas)), ", ") + ")"
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES ", l.tabela, strings.Join(l.colunas, ", "))
+	for i := 0; i < linhas; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(linha)
+	}
+	return sb.String()
+}
+
+// tamanhoLinha estima os bytes que a linha ocupa no pacote enviado
+func tamanhoLinha(valores []interface{}) int {
+	total := 0
+	for _, v := range valores {
+		total += custoFixoValor
+		switch v := v.(type) {
+		case string:
+			total += len(v)
+		case *string:
+			if v != nil {
+				total += len(*v)
+			}
+		case []byte:
+			total += len(v)
+		}
+	}
+	return total
+}
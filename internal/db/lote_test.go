@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// driverFalso é um driver database/sql em memória que só registra os
+// INSERTs executados, para testar o loteInsert sem um servidor MySQL
+type driverFalso struct{}
+
+// servidorFalso guarda o que o driverFalso recebeu
+var servidorFalso struct {
+	sync.Mutex
+	lotes    []int // parâmetros de cada Exec
+	preparos int
+}
+
+func init() {
+	sql.Register("falso", driverFalso{})
+}
+
+func (driverFalso) Open(string) (driver.Conn, error) { return conexaoFalsa{}, nil }
+
+type conexaoFalsa struct{}
+
+func (conexaoFalsa) Prepare(query string) (driver.Stmt, error) {
+	servidorFalso.Lock()
+	servidorFalso.preparos++
+	servidorFalso.Unlock()
+	return instrucaoFalsa{parametros: strings.Count(query, "?")}, nil
+}
+func (conexaoFalsa) Close() error              { return nil }
+func (conexaoFalsa) Begin() (driver.Tx, error) { return transacaoFalsa{}, nil }
+
+type transacaoFalsa struct{}
+
+func (transacaoFalsa) Commit() error   { return nil }
+func (transacaoFalsa) Rollback() error { return nil }
+
+type instrucaoFalsa struct{ parametros int }
+
+func (s instrucaoFalsa) Close() error  { return nil }
+func (s instrucaoFalsa) NumInput() int { return s.parametros }
+
+func (s instrucaoFalsa) Exec(args []driver.Value) (driver.Result, error) {
+	servidorFalso.Lock()
+	servidorFalso.lotes = append(servidorFalso.lotes, len(args))
+	servidorFalso.Unlock()
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s instrucaoFalsa) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("consulta não suportada")
+}
+
+// abrirTransacaoFalsa zera o servidorFalso e abre uma transação nele
+func abrirTransacaoFalsa(t testing.TB) *sql.Tx {
+	t.Helper()
+	servidorFalso.Lock()
+	servidorFalso.lotes = nil
+	servidorFalso.preparos = 0
+	servidorFalso.Unlock()
+
+	db, err := sql.Open("falso", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// linhasPorLote converte os parâmetros de cada Exec em linhas
+func linhasPorLote(colunas int) []int {
+	servidorFalso.Lock()
+	defer servidorFalso.Unlock()
+	linhas := make([]int, len(servidorFalso.lotes))
+	for i, n := range servidorFalso.lotes {
+		linhas[i] = n / colunas
+	}
+	return linhas
+}
+
+func colunasTeste(n int) []string {
+	colunas := make([]string, n)
+	for i := range colunas {
+		colunas[i] = fmt.Sprintf("c%d", i)
+	}
+	return colunas
+}
+
+func linhaTeste(colunas int, valor string) []interface{} {
+	linha := make([]interface{}, colunas)
+	for i := range linha {
+		linha[i] = valor
+	}
+	return linha
+}
+
+func TestLoteInsertDivisao(t *testing.T) {
+	// tamanhoLinha de uma linha de 2 colunas com strings de 10 bytes
+	const bytesLinha = 2 * (custoFixoValor + 10)
+
+	casos := []struct {
+		nome     string
+		colunas  int
+		limite   int
+		linhas   int
+		esperado []int
+	}{
+		{"cabe num lote", 2, 1 << 20, 10, []int{10}},
+		{"limite de linhas", 2, 1 << 20, 2500, []int{maxLinhasLote, maxLinhasLote, 500}},
+		{"limite de placeholders", 100, 1 << 30, 1000, []int{655, 345}},
+		{"limite de bytes", 2, 3 * bytesLinha, 7, []int{3, 3, 1}},
+		{"linha maior que o limite vai sozinha", 2, bytesLinha / 2, 3, []int{1, 1, 1}},
+		{"sem linhas", 2, 1 << 20, 0, []int{}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			tx := abrirTransacaoFalsa(t)
+			lote := novoLoteInsert(context.Background(), tx, c.limite, "tabela", colunasTeste(c.colunas)...)
+			for i := 0; i < c.linhas; i++ {
+				if err := lote.Adicionar(linhaTeste(c.colunas, "0123456789")...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := lote.Concluir(); err != nil {
+				t.Fatal(err)
+			}
+
+			obtido := linhasPorLote(c.colunas)
+			if fmt.Sprint(obtido) != fmt.Sprint(c.esperado) {
+				t.Errorf("linhas por INSERT = %v, esperado %v", obtido, c.esperado)
+			}
+		})
+	}
+}
+
+func TestLoteInsertReaproveitaInstrucoes(t *testing.T) {
+	tx := abrirTransacaoFalsa(t)
+	lote := novoLoteInsert(context.Background(), tx, 1<<20, "tabela", "a", "b")
+	for i := 0; i < 3*maxLinhasLote+1; i++ {
+		if err := lote.Adicionar("x", "y"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lote.Concluir(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Três lotes cheios usam a mesma instrução; o último, de 1 linha, outra
+	if servidorFalso.preparos != 2 {
+		t.Errorf("%d instruções preparadas, esperado 2", servidorFalso.preparos)
+	}
+}
+
+func TestLoteInsertQuantidadeDeValores(t *testing.T) {
+	tx := abrirTransacaoFalsa(t)
+	lote := novoLoteInsert(context.Background(), tx, 1<<20, "tabela", "a", "b")
+	if err := lote.Adicionar("so um"); err == nil {
+		t.Error("linha com menos valores que colunas deveria falhar")
+	}
+}
+
+func TestLoteInsertSQL(t *testing.T) {
+	lote := novoLoteInsert(context.Background(), nil, 1<<20, "accounts", "id", "login")
+	esperado := "INSERT INTO accounts (id, login) VALUES (?, ?), (?, ?)"
+	if obtido := lote.sql(2); obtido != esperado {
+		t.Errorf("sql(2) = %q, esperado %q", obtido, esperado)
+	}
+}
+
+func TestLoteInsertCancelado(t *testing.T) {
+	tx := abrirTransacaoFalsa(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	lote := novoLoteInsert(ctx, tx, 1<<20, "tabela", "a")
+	lote.Adicionar("x")
+	if err := lote.Concluir(); err == nil {
+		t.Error("Concluir com ctx cancelado deveria falhar")
+	}
+}
+
+// BenchmarkInserirLote compara a carga linha a linha (um INSERT por linha,
+// como antes dos lotes) com INSERTs de várias linhas. O benchmark é
+// sintético: o driver falso não fala com um MySQL, então o tempo medido é
+// só o de montar as instruções. O ganho real vem de cada instrução custar
+// uma ida e volta ao servidor, e isso aparece em instrucoes/linha.
+func BenchmarkInserirLote(b *testing.B) {
+	const (
+		linhas  = 2000
+		colunas = 8
+	)
+	for _, caso := range []struct {
+		nome      string
+		maxLinhas int
+	}{
+		{"linha_a_linha", 1},
+		{"varias_linhas", maxLinhasLote},
+	} {
+		b.Run(caso.nome, func(b *testing.B) {
+			linha := linhaTeste(colunas, "valor de teste")
+			var instrucoes int
+			for i := 0; i < b.N; i++ {
+				tx := abrirTransacaoFalsa(b)
+				lote := novoLoteInsert(context.Background(), tx, 1<<20, "tabela", colunasTeste(colunas)...)
+				lote.maxLinhas = caso.maxLinhas
+				for j := 0; j < linhas; j++ {
+					if err := lote.Adicionar(linha...); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := lote.Concluir(); err != nil {
+					b.Fatal(err)
+				}
+				instrucoes += len(linhasPorLote(colunas))
+			}
+			b.ReportMetric(float64(instrucoes)/float64(linhas*b.N), "instrucoes/linha")
+		})
+	}
+}
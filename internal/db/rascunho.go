@@ -0,0 +1,152 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// PrefixoRascunho identifica os bancos temporários criados para cada
+// conversão. O nome completo é prefixo + unix timestamp + "_" + sufixo
+// aleatório, o que permite saber a idade de um banco esquecido.
+const PrefixoRascunho = "conversao_tmp_"
+
+// CriarBancoRascunho cria um banco temporário exclusivo para uma conversão,
+// com as tabelas do banco da DSN (o modelo do painel) clonadas via CREATE
+// TABLE LIKE. Retorna o nome do banco e a DSN para acessá-lo.
+func CriarBancoRascunho(dsn string) (string, string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("DSN inválida: %v", err)
+	}
+	modelo := cfg.DBName
+
+	sufixo := make([]byte, 4)
+	if _, err := rand.Read(sufixo); err != nil {
+		return "", "", fmt.Errorf("erro ao gerar nome do banco temporário: %v", err)
+	}
+	nome := fmt.Sprintf("%s%d_%s", PrefixoRascunho, time.Now().Unix(), hex.EncodeToString(sufixo))
+
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("erro ao conectar ao MySQL: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", nome))
+	if err != nil {
+		return "", "", fmt.Errorf("erro ao criar banco temporário: %v", err)
+	}
+
+	// Clona a estrutura das tabelas do modelo
+	rows, err := db.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE'", modelo)
+	if err != nil {
+		removerBanco(dsn, nome)
+		return "", "", fmt.Errorf("erro ao listar tabelas de %s: %v", modelo, err)
+	}
+	var tabelas []string
+	for rows.Next() {
+		var tabela string
+		if err := rows.Scan(&tabela); err != nil {
+			rows.Close()
+			removerBanco(dsn, nome)
+			return "", "", fmt.Errorf("erro ao listar tabelas de %s: %v", modelo, err)
+		}
+		tabelas = append(tabelas, tabela)
+	}
+	rows.Close()
+
+	for _, tabela := range tabelas {
+		_, err := db.Exec(fmt.Sprintf("CREATE TABLE `%s`.`%s` LIKE `%s`.`%s`", nome, tabela, modelo, tabela))
+		if err != nil {
+			removerBanco(dsn, nome)
+			return "", "", fmt.Errorf("erro ao clonar tabela %s: %v", tabela, err)
+		}
+	}
+
+	cfg.DBName = nome
+	return nome, cfg.FormatDSN(), nil
+}
+
+// RemoverBancoRascunho apaga um banco criado por CriarBancoRascunho. Só
+// aceita nomes com o prefixo dos bancos temporários.
+func RemoverBancoRascunho(dsn, nome string) error {
+	if !strings.HasPrefix(nome, PrefixoRascunho) {
+		return fmt.Errorf("banco %s não é temporário", nome)
+	}
+	return removerBanco(dsn, nome)
+}
+
+// RemoverBancosOrfaos apaga os bancos temporários com mais de idadeMinima,
+// deixados para trás por conversões interrompidas. Só são apagados os
+// bancos cujo nome traz a data de criação. Retorna quantos removeu.
+func RemoverBancosOrfaos(dsn string, idadeMinima time.Duration) (int, error) {
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao conectar ao MySQL: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE ?",
+		strings.ReplaceAll(PrefixoRascunho, "_", `\_`)+"%")
+	if err != nil {
+		return 0, fmt.Errorf("erro ao listar bancos temporários: %v", err)
+	}
+	var nomes []string
+	for rows.Next() {
+		var nome string
+		if err := rows.Scan(&nome); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("erro ao listar bancos temporários: %v", err)
+		}
+		nomes = append(nomes, nome)
+	}
+	rows.Close()
+
+	removidos := 0
+	limite := time.Now().Add(-idadeMinima)
+	for _, nome := range nomes {
+		// Um nome fora do formato pode ser de outra versão do programa, que
+		// não dá para saber se ainda o usa
+		if criado, ok := criacaoRascunho(nome); !ok || criado.After(limite) {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", nome)); err != nil {
+			return removidos, fmt.Errorf("erro ao remover banco %s: %v", nome, err)
+		}
+		removidos++
+	}
+	return removidos, nil
+}
+
+// criacaoRascunho extrai do nome o momento em que o banco foi criado
+func criacaoRascunho(nome string) (time.Time, bool) {
+	resto := strings.TrimPrefix(nome, PrefixoRascunho)
+	i := strings.Index(resto, "_")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	segundos, err := strconv.ParseInt(resto[:i], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(segundos, 0), true
+}
+
+func removerBanco(dsn, nome string) error {
+	db, err := OpenDB(dsn)
+	if err != nil {
+		return fmt.Errorf("erro ao conectar ao MySQL: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", nome)); err != nil {
+		return fmt.Errorf("erro ao remover banco %s: %v", nome, err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCriacaoRascunho(t *testing.T) {
+	casos := []struct {
+		nome      string
+		criado    time.Time
+		conhecido bool
+	}{
+		{PrefixoRascunho + "1700000000_0a1b2c3d", time.Unix(1700000000, 0), true},
+		{PrefixoRascunho + "0_ff", time.Unix(0, 0), true},
+		{PrefixoRascunho + "1700000000", time.Time{}, false},
+		{PrefixoRascunho + "abc_0a1b2c3d", time.Time{}, false},
+		{PrefixoRascunho + "_0a1b2c3d", time.Time{}, false},
+		{PrefixoRascunho + "manual", time.Time{}, false},
+	}
+	for _, c := range casos {
+		criado, ok := criacaoRascunho(c.nome)
+		if ok != c.conhecido || !criado.Equal(c.criado) {
+			t.Errorf("criacaoRascunho(%q) = %v, %v; esperado %v, %v", c.nome, criado, ok, c.criado, c.conhecido)
+		}
+	}
+}
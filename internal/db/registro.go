@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"conversao-db/internal/conversao"
+)
+
+// Registra os carregadores MySQL dos conversores. Fica aqui porque o pacote
+// conversao não pode importar db.
+func init() {
+	conversao.DefinirCarregador("eclipse", "atlas", func(ctx context.Context, dados conversao.Dados, dsn string, acomp *conversao.Acompanhamento) error {
+		dbExport, ok := dados.(*conversao.DatabaseExport)
+		if !ok {
+			return fmt.Errorf("dados inesperados para carga do Eclipse: %T", dados)
+		}
+		return EnviarParaMySQL(ctx, dbExport, dsn, acomp)
+	})
+	conversao.DefinirCarregador("atlas", "atlas", func(ctx context.Context, dados conversao.Dados, dsn string, acomp *conversao.Acompanhamento) error {
+		dbFinal, ok := dados.(*conversao.DatabaseFinal)
+		if !ok {
+			return fmt.Errorf("dados inesperados para carga do Atlas: %T", dados)
+		}
+		return EnviarParaMySQLFinal(ctx, dbFinal, dsn, acomp)
+	})
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// executarEmTransacao roda fn dentro de uma transação. Se fn falhar ou
+// entrar em panic, tudo é desfeito e o banco fica como estava antes. Cancelar
+// ctx também desfaz a transação.
+func executarEmTransacao(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			return fmt.Errorf("%v (erro ao desfazer transação: %v)", err, errRollback)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação: %v", err)
+	}
+	return nil
+}
+
+// apagarTabelas remove os registros das tabelas dentro da transação. Usa
+// DELETE porque TRUNCATE faz commit implícito no MySQL e não seria desfeito
+// numa falha.
+func apagarTabelas(tx *sql.Tx, tabelas []string) error {
+	// Desabilitar verificação de chave estrangeira na conexão da transação
+	if _, err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return fmt.Errorf("erro ao desabilitar foreign key checks: %v", err)
+	}
+
+	for _, tabela := range tabelas {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s", tabela)); err != nil {
+			return fmt.Errorf("erro ao limpar tabela %s: %v", tabela, err)
+		}
+	}
+
+	if _, err := tx.Exec("SET FOREIGN_KEY_CHECKS = 1"); err != nil {
+		return fmt.Errorf("erro ao reabilitar foreign key checks: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EscreverBanco grava um dump com todas as tabelas do banco conectado. A
+// leitura acontece numa única transação somente leitura, para que o dump
+// seja consistente. Cancelar ctx interrompe a leitura.
+func EscreverBanco(ctx context.Context, db *sql.DB, w io.Writer) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar leitura do banco: %v", err)
+	}
+	defer tx.Rollback()
+
+	tabelas, err := listarTabelas(tx)
+	if err != nil {
+		return err
+	}
+
+	e := NovoEscritor(w)
+	for _, tabela := range tabelas {
+		if err := escreverTabela(tx, e, tabela); err != nil {
+			return err
+		}
+	}
+	return e.Concluir()
+}
+
+func listarTabelas(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar tabelas: %v", err)
+	}
+	defer rows.Close()
+
+	var tabelas []string
+	for rows.Next() {
+		var tabela string
+		if err := rows.Scan(&tabela); err != nil {
+			return nil, fmt.Errorf("erro ao listar tabelas: %v", err)
+		}
+		tabelas = append(tabelas, tabela)
+	}
+	return tabelas, rows.Err()
+}
+
+func escreverTabela(tx *sql.Tx, e *Escritor, tabela string) error {
+	var nome, create string
+	if err := tx.QueryRow("SHOW CREATE TABLE "+identificador(tabela)).Scan(&nome, &create); err != nil {
+		return fmt.Errorf("erro ao ler a estrutura de %s: %v", tabela, err)
+	}
+
+	rows, err := tx.Query("SELECT * FROM " + identificador(tabela))
+	if err != nil {
+		return fmt.Errorf("erro ao ler a tabela %s: %v", tabela, err)
+	}
+	defer rows.Close()
+
+	tipos, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("erro ao ler as colunas de %s: %v", tabela, err)
+	}
+	colunas := make([]string, len(tipos))
+	for i, t := range tipos {
+		colunas[i] = t.Name()
+	}
+	e.Tabela(tabela, create, colunas)
+
+	brutos := make([]sql.RawBytes, len(tipos))
+	destinos := make([]interface{}, len(tipos))
+	for i := range brutos {
+		destinos[i] = &brutos[i]
+	}
+	valores := make([]interface{}, len(tipos))
+
+	for rows.Next() {
+		if err := rows.Scan(destinos...); err != nil {
+			return fmt.Errorf("erro ao ler a tabela %s: %v", tabela, err)
+		}
+		for i, b := range brutos {
+			valores[i] = valorColuna(tipos[i].DatabaseTypeName(), b)
+		}
+		e.Linha(valores...)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("erro ao ler a tabela %s: %v", tabela, err)
+	}
+	return nil
+}
+
+// valorColuna converte o conteúdo bruto de uma coluna conforme o tipo: NULL,
+// número sem aspas, binário em hexadecimal ou texto
+func valorColuna(tipo string, b sql.RawBytes) interface{} {
+	if b == nil {
+		return nil
+	}
+	tipo = strings.ToUpper(tipo)
+	switch {
+	case strings.Contains(tipo, "INT"), tipo == "DECIMAL", tipo == "FLOAT", tipo == "DOUBLE", tipo == "YEAR":
+		return Bruto(string(b))
+	case strings.Contains(tipo, "BLOB"), strings.Contains(tipo, "BINARY"), tipo == "BIT", tipo == "GEOMETRY":
+		return Binario(append([]byte(nil), b...))
+	default:
+		return string(b)
+	}
+}
@@ -0,0 +1,231 @@
+// Package dump grava arquivos SQL restauráveis (CREATE TABLE + INSERTs
+// estendidos) sem depender do mysqldump.
+package dump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"conversao-db/internal/conversao"
+)
+
+// limiteInsert é o tamanho aproximado de cada INSERT estendido, o mesmo
+// net_buffer_length padrão usado pelo mysqldump
+const limiteInsert = 1024 * 1024
+
+// Bruto é um literal SQL gravado sem aspas nem escape, como números lidos
+// de um banco
+type Bruto string
+
+// Binario é gravado como literal hexadecimal (0x...)
+type Binario []byte
+
+// Escritor grava um dump SQL. Os erros de escrita ficam guardados e são
+// devolvidos por Concluir, de modo que as chamadas intermediárias podem
+// ignorá-los.
+type Escritor struct {
+	w   *bufio.Writer
+	err error
+
+	tabela   string
+	colunas  string
+	tamanho  int
+	iniciado bool
+}
+
+// NovoEscritor cria um escritor sobre w e grava o cabeçalho do dump
+func NovoEscritor(w io.Writer) *Escritor {
+	e := &Escritor{w: bufio.NewWriterSize(w, 64*1024)}
+	e.escrever("-- Dump SQL gerado por conversao-db\n")
+	e.escrever(fmt.Sprintf("-- Data: %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
+	e.escrever("/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n")
+	e.escrever("/*!40101 SET NAMES utf8mb4 */;\n")
+	e.escrever("/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;\n")
+	e.escrever("/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n")
+	e.escrever("/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;\n\n")
+	return e
+}
+
+// Tabela grava DROP TABLE e o CREATE TABLE informado e prepara os INSERTs
+// com as colunas da tabela
+func (e *Escritor) Tabela(nome, create string, colunas []string) {
+	e.encerrarInsert()
+
+	e.escrever(fmt.Sprintf("--\n-- Tabela %s\n--\n\n", identificador(nome)))
+	e.escrever(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", identificador(nome)))
+	e.escrever(strings.TrimSpace(create) + ";\n\n")
+
+	nomes := make([]string, len(colunas))
+	for i, c := range colunas {
+		nomes[i] = identificador(c)
+	}
+	e.tabela = nome
+	e.colunas = strings.Join(nomes, ", ")
+}
+
+// Linha acrescenta uma linha à tabela corrente, abrindo um novo INSERT
+// quando o atual passa do limite
+func (e *Escritor) Linha(valores ...interface{}) {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for i, v := range valores {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(Literal(v))
+	}
+	sb.WriteByte(')')
+	linha := sb.String()
+
+	if e.iniciado && e.tamanho+len(linha) > limiteInsert {
+		e.encerrarInsert()
+	}
+	if !e.iniciado {
+		e.escrever(fmt.Sprintf("INSERT INTO %s (%s) VALUES ", identificador(e.tabela), e.colunas))
+		e.iniciado = true
+		e.tamanho = 0
+	} else {
+		e.escrever(",")
+	}
+	e.escrever(linha)
+	e.tamanho += len(linha)
+}
+
+// Concluir encerra o último INSERT, grava o rodapé que restaura as
+// variáveis da sessão e descarrega o buffer
+func (e *Escritor) Concluir() error {
+	e.encerrarInsert()
+	e.escrever("/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n")
+	e.escrever("/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;\n")
+	e.escrever("/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS */;\n")
+	e.escrever("/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;\n")
+	if e.err != nil {
+		return fmt.Errorf("erro ao gravar dump: %v", e.err)
+	}
+	if err := e.w.Flush(); err != nil {
+		return fmt.Errorf("erro ao gravar dump: %v", err)
+	}
+	return nil
+}
+
+func (e *Escritor) encerrarInsert() {
+	if e.iniciado {
+		e.escrever(";\n\n")
+		e.iniciado = false
+	}
+}
+
+func (e *Escritor) escrever(s string) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(s)
+}
+
+// EscreverTabelas grava um dump com as tabelas convertidas
+func EscreverTabelas(w io.Writer, tabelas []conversao.TabelaDestino) error {
+	e := NovoEscritor(w)
+	for _, t := range tabelas {
+		e.Tabela(t.Nome, fmt.Sprintf("CREATE TABLE %s %s", identificador(t.Nome), t.Definicao), t.Colunas)
+		for _, linha := range t.Linhas {
+			e.Linha(linha...)
+		}
+	}
+	return e.Concluir()
+}
+
+// EscreverArquivo grava em arquivo o dump das tabelas convertidas, sem
+// precisar de um servidor MySQL
+func EscreverArquivo(caminho string, tabelas []conversao.TabelaDestino) error {
+	out, err := os.Create(caminho)
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo: %v", err)
+	}
+	if err := EscreverTabelas(out, tabelas); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Literal formata um valor Go como literal SQL
+func Literal(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case Bruto:
+		return string(v)
+	case Binario:
+		if len(v) == 0 {
+			return "''"
+		}
+		return fmt.Sprintf("0x%X", []byte(v))
+	case string:
+		return texto(v)
+	case []byte:
+		return texto(string(v))
+	case *string:
+		if v == nil {
+			return "NULL"
+		}
+		return texto(*v)
+	case *int:
+		if v == nil {
+			return "NULL"
+		}
+		return strconv.Itoa(*v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return texto(v.Format("2006-01-02 15:04:05"))
+	default:
+		return texto(fmt.Sprint(v))
+	}
+}
+
+// texto coloca o valor entre aspas com os escapes do mysqldump
+func texto(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			sb.WriteString(`\0`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\'':
+			sb.WriteString(`\'`)
+		case '"':
+			sb.WriteString(`\"`)
+		case 0x1a:
+			sb.WriteString(`\Z`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+func identificador(nome string) string {
+	return "`" + strings.ReplaceAll(nome, "`", "``") + "`"
+}
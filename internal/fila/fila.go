@@ -0,0 +1,338 @@
+// Package fila guarda em disco os trabalhos de conversão, para que a fila
+// sobreviva a reinícios do processo.
+package fila
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Estado de um trabalho na fila
+type Estado string
+
+const (
+	NaFila      Estado = "queued"
+	Processando Estado = "running"
+	Concluido   Estado = "done"
+	Falhou      Estado = "failed"
+	Cancelado   Estado = "canceled"
+)
+
+// retencao é por quanto tempo trabalhos encerrados continuam no arquivo
+const retencao = 7 * 24 * time.Hour
+
+// maxTentativas é quantas vezes um trabalho pode começar a ser processado.
+// Um trabalho interrompido por uma queda do processo volta para a fila ao
+// reiniciar; se o próprio trabalho derruba o processo (falta de memória,
+// por exemplo), ele é encerrado como Falhou em vez de derrubá-lo de novo.
+const maxTentativas = 3
+
+// Registro é um trabalho guardado na fila. Dados é o trabalho em si,
+// serializado por quem o enfileirou.
+type Registro struct {
+	ID         string          `json:"id"`
+	Dono       string          `json:"dono"`
+	Estado     Estado          `json:"estado"`
+	Dados      json.RawMessage `json:"dados"`
+	Erro       string          `json:"erro,omitempty"`
+	Tentativas int             `json:"tentativas,omitempty"` // vezes que começou a ser processado
+	Criado     time.Time       `json:"criado"`
+	Atualizado time.Time       `json:"atualizado"`
+}
+
+// Armazem é uma fila persistida num arquivo JSON, regravado por inteiro a
+// cada mudança (por arquivo temporário + rename, para não corromper o
+// arquivo numa queda no meio da escrita). A fila é pequena, então isso basta.
+type Armazem struct {
+	caminho string
+
+	mu        sync.Mutex
+	registros []*Registro
+	aviso     chan struct{}
+
+	// ultimoInicio guarda quando cada dono teve um trabalho iniciado, para
+	// revezar entre donos em Proximo
+	ultimoInicio map[string]time.Time
+}
+
+// Abrir carrega a fila do arquivo, criando-o se não existir. Trabalhos que
+// estavam em processamento quando o processo parou voltam para a fila, até
+// maxTentativas vezes.
+func Abrir(caminho string) (*Armazem, error) {
+	a := &Armazem{caminho: caminho, aviso: make(chan struct{}, 1), ultimoInicio: make(map[string]time.Time)}
+
+	conteudo, err := os.ReadFile(caminho)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("erro ao ler a fila: %v", err)
+	default:
+		if err := json.Unmarshal(conteudo, &a.registros); err != nil {
+			return nil, fmt.Errorf("erro ao ler a fila %s: %v", caminho, err)
+		}
+	}
+
+	agora := time.Now()
+	var mantidos []*Registro
+	for _, r := range a.registros {
+		if r.Estado == Processando {
+			r.Estado = NaFila
+			if r.Tentativas >= maxTentativas {
+				r.Estado = Falhou
+				r.Erro = fmt.Sprintf("o processamento foi interrompido %d vezes", r.Tentativas)
+			}
+			r.Atualizado = agora
+		}
+		if encerrado(r.Estado) && agora.Sub(r.Atualizado) > retencao {
+			continue
+		}
+		mantidos = append(mantidos, r)
+	}
+	a.registros = mantidos
+
+	if err := a.salvar(); err != nil {
+		return nil, err
+	}
+	if len(a.Pendentes()) > 0 {
+		a.avisar()
+	}
+	return a, nil
+}
+
+// Enfileirar grava um novo trabalho de dono no fim da fila
+func (a *Armazem) Enfileirar(id, dono string, dados interface{}) error {
+	conteudo, err := json.Marshal(dados)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar trabalho: %v", err)
+	}
+
+	a.mu.Lock()
+	agora := time.Now()
+	a.registros = append(a.registros, &Registro{
+		ID:         id,
+		Dono:       dono,
+		Estado:     NaFila,
+		Dados:      conteudo,
+		Criado:     agora,
+		Atualizado: agora,
+	})
+	err = a.salvar()
+	a.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	a.avisar()
+	return nil
+}
+
+// Proximo marca como em processamento e devolve o próximo trabalho da fila.
+// Para que um dono não monopolize a fila, a vez é de quem tem menos
+// trabalhos em processamento e, entre esses, de quem foi atendido há mais
+// tempo; os trabalhos de um mesmo dono saem na ordem em que entraram. Sem
+// trabalhos, espera até que um seja enfileirado.
+func (a *Armazem) Proximo() (Registro, error) {
+	for {
+		a.mu.Lock()
+		if r := a.escolher(); r != nil {
+			r.Estado = Processando
+			r.Tentativas++
+			r.Atualizado = time.Now()
+			a.ultimoInicio[r.Dono] = r.Atualizado
+			err := a.salvar()
+			copia := *r
+			a.mu.Unlock()
+			// Pode haver mais trabalhos para outros workers
+			a.avisar()
+			return copia, err
+		}
+		a.mu.Unlock()
+		<-a.aviso
+	}
+}
+
+// escolher devolve o trabalho da vez, ou nil se a fila estiver vazia; deve
+// ser chamado com a.mu travado
+func (a *Armazem) escolher() *Registro {
+	emProcessamento := make(map[string]int)
+	for _, r := range a.registros {
+		if r.Estado == Processando {
+			emProcessamento[r.Dono]++
+		}
+	}
+
+	var escolhido *Registro
+	vistos := make(map[string]bool)
+	for _, r := range a.registros {
+		// Só o trabalho mais antigo de cada dono concorre
+		if r.Estado != NaFila || vistos[r.Dono] {
+			continue
+		}
+		vistos[r.Dono] = true
+		if escolhido == nil || antes(r, escolhido, emProcessamento, a.ultimoInicio) {
+			escolhido = r
+		}
+	}
+	return escolhido
+}
+
+// antes informa se r tem a vez antes de outro
+func antes(r, outro *Registro, emProcessamento map[string]int, ultimoInicio map[string]time.Time) bool {
+	if emProcessamento[r.Dono] != emProcessamento[outro.Dono] {
+		return emProcessamento[r.Dono] < emProcessamento[outro.Dono]
+	}
+	if !ultimoInicio[r.Dono].Equal(ultimoInicio[outro.Dono]) {
+		return ultimoInicio[r.Dono].Before(ultimoInicio[outro.Dono])
+	}
+	return r.Criado.Before(outro.Criado)
+}
+
+// Posicao estima a posição (a partir de 1) de um trabalho na fila, seguindo
+// o revezamento de Proximo: o k-ésimo trabalho de cada dono sai na k-ésima
+// rodada. Retorna 0 se o trabalho não está aguardando.
+func (a *Armazem) Posicao(id string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	type vez struct {
+		rodada int
+		r      *Registro
+	}
+	var vezes []vez
+	alvo := -1
+	rodadas := make(map[string]int)
+	for _, r := range a.registros {
+		if r.Estado != NaFila {
+			continue
+		}
+		if r.ID == id {
+			alvo = len(vezes)
+		}
+		vezes = append(vezes, vez{rodada: rodadas[r.Dono], r: r})
+		rodadas[r.Dono]++
+	}
+	if alvo < 0 {
+		return 0
+	}
+
+	posicao := 1
+	for i, v := range vezes {
+		if i == alvo {
+			continue
+		}
+		if v.rodada < vezes[alvo].rodada ||
+			(v.rodada == vezes[alvo].rodada && antes(v.r, vezes[alvo].r, nil, a.ultimoInicio)) {
+			posicao++
+		}
+	}
+	return posicao
+}
+
+// Encerrar grava o resultado de um trabalho em processamento: Concluido,
+// Cancelado, ou Falhou com a mensagem de erro
+func (a *Armazem) Encerrar(id string, estado Estado, erro string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, r := range a.registros {
+		if r.ID == id {
+			r.Estado = estado
+			r.Erro = erro
+			r.Atualizado = time.Now()
+			return a.salvar()
+		}
+	}
+	return fmt.Errorf("trabalho %s não encontrado na fila", id)
+}
+
+// Cancelar cancela um trabalho de dono. Um trabalho aguardando sai da fila
+// na hora; para um em processamento, o estado devolvido é Processando e cabe
+// a quem o executa interrompê-lo e encerrá-lo como Cancelado.
+func (a *Armazem) Cancelar(id, dono string) (Estado, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, r := range a.registros {
+		if r.ID != id || r.Dono != dono {
+			continue
+		}
+		switch r.Estado {
+		case NaFila:
+			r.Estado = Cancelado
+			r.Atualizado = time.Now()
+			return NaFila, a.salvar()
+		case Processando:
+			return Processando, nil
+		default:
+			return r.Estado, fmt.Errorf("trabalho %s já encerrado", id)
+		}
+	}
+	return "", fmt.Errorf("trabalho %s não encontrado na fila", id)
+}
+
+// Listar devolve os trabalhos de dono, do mais antigo ao mais novo
+func (a *Armazem) Listar(dono string) []Registro {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var lista []Registro
+	for _, r := range a.registros {
+		if r.Dono == dono {
+			lista = append(lista, *r)
+		}
+	}
+	return lista
+}
+
+// Pendentes devolve os trabalhos ainda não encerrados, na ordem da fila
+func (a *Armazem) Pendentes() []Registro {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var pendentes []Registro
+	for _, r := range a.registros {
+		if !encerrado(r.Estado) {
+			pendentes = append(pendentes, *r)
+		}
+	}
+	return pendentes
+}
+
+// avisar acorda quem espera em Proximo, sem bloquear
+func (a *Armazem) avisar() {
+	select {
+	case a.aviso <- struct{}{}:
+	default:
+	}
+}
+
+// salvar regrava o arquivo da fila; deve ser chamado com a.mu travado
+func (a *Armazem) salvar() error {
+	conteudo, err := json.MarshalIndent(a.registros, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar a fila: %v", err)
+	}
+	if dir := filepath.Dir(a.caminho); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("erro ao criar diretório da fila: %v", err)
+		}
+	}
+	tmp := a.caminho + ".tmp"
+	// A fila guarda dados dos usuários (ids de chat e de arquivos), então só
+	// o dono do processo lê o arquivo
+	if err := os.WriteFile(tmp, conteudo, 0600); err != nil {
+		return fmt.Errorf("erro ao gravar a fila: %v", err)
+	}
+	if err := os.Rename(tmp, a.caminho); err != nil {
+		return fmt.Errorf("erro ao gravar a fila: %v", err)
+	}
+	return nil
+}
+
+func encerrado(e Estado) bool {
+	return e == Concluido || e == Falhou || e == Cancelado
+}
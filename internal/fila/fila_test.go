@@ -0,0 +1,242 @@
+package fila
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func abrirFila(t *testing.T, caminho string) *Armazem {
+	t.Helper()
+	a, err := Abrir(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func enfileirar(t *testing.T, a *Armazem, dono string, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if err := a.Enfileirar(id, dono, map[string]string{"arquivo": id + ".sql"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func proximo(t *testing.T, a *Armazem) Registro {
+	t.Helper()
+	r, err := a.Proximo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// registro lê o trabalho id do arquivo da fila
+func registro(t *testing.T, caminho, id string) Registro {
+	t.Helper()
+	for _, r := range lerArquivo(t, caminho) {
+		if r.ID == id {
+			return r
+		}
+	}
+	t.Fatalf("trabalho %s não encontrado", id)
+	return Registro{}
+}
+
+func lerArquivo(t *testing.T, caminho string) []Registro {
+	t.Helper()
+	conteudo, err := os.ReadFile(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registros []Registro
+	if err := json.Unmarshal(conteudo, &registros); err != nil {
+		t.Fatal(err)
+	}
+	return registros
+}
+
+func TestAbrirRetomaInterrompidos(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "fila", "fila.json")
+	a := abrirFila(t, caminho)
+	enfileirar(t, a, "1", "a", "b")
+
+	// A cada queda no meio do processamento, o trabalho volta para a fila
+	for tentativa := 1; tentativa < maxTentativas; tentativa++ {
+		if r := proximo(t, a); r.ID != "a" || r.Tentativas != tentativa {
+			t.Fatalf("Proximo = %s (tentativa %d), esperado a (tentativa %d)", r.ID, r.Tentativas, tentativa)
+		}
+		a = abrirFila(t, caminho)
+		if r := registro(t, caminho, "a"); r.Estado != NaFila {
+			t.Fatalf("depois da tentativa %d, estado = %s, esperado %s", tentativa, r.Estado, NaFila)
+		}
+	}
+
+	// Na última tentativa interrompida, ele é encerrado como falho
+	proximo(t, a)
+	a = abrirFila(t, caminho)
+	r := registro(t, caminho, "a")
+	if r.Estado != Falhou || r.Erro == "" {
+		t.Fatalf("estado = %s (%q), esperado %s com o motivo", r.Estado, r.Erro, Falhou)
+	}
+	var dados map[string]string
+	if err := json.Unmarshal(r.Dados, &dados); err != nil || dados["arquivo"] != "a.sql" {
+		t.Errorf("dados = %s (%v), esperado o trabalho enfileirado", r.Dados, err)
+	}
+	if r := proximo(t, a); r.ID != "b" {
+		t.Errorf("Proximo = %s, esperado b", r.ID)
+	}
+}
+
+func TestAbrirDescartaEncerradosAntigos(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "fila.json")
+	agora := time.Now()
+	registros := []Registro{
+		{ID: "antigo", Dono: "1", Estado: Concluido, Atualizado: agora.Add(-retencao - time.Hour)},
+		{ID: "recente", Dono: "1", Estado: Falhou, Atualizado: agora.Add(-time.Hour)},
+		{ID: "esperando", Dono: "1", Estado: NaFila, Atualizado: agora.Add(-retencao - time.Hour)},
+	}
+	conteudo, _ := json.Marshal(registros)
+	if err := os.WriteFile(caminho, conteudo, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	abrirFila(t, caminho)
+	var ids []string
+	for _, r := range lerArquivo(t, caminho) {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 2 || ids[0] != "recente" || ids[1] != "esperando" {
+		t.Errorf("trabalhos mantidos = %v, esperado [recente esperando]", ids)
+	}
+}
+
+func TestProximoReveza(t *testing.T) {
+	a := abrirFila(t, filepath.Join(t.TempDir(), "fila.json"))
+	enfileirar(t, a, "A", "a1", "a2", "a3")
+	enfileirar(t, a, "B", "b1", "b2")
+	enfileirar(t, a, "C", "c1")
+
+	// Cada dono tem a vez numa rodada, e os trabalhos de cada um saem na
+	// ordem em que entraram
+	var ordem []string
+	for range 6 {
+		r := proximo(t, a)
+		ordem = append(ordem, r.ID)
+		if err := a.Encerrar(r.ID, Concluido, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fmt.Sprint(ordem) != "[a1 b1 c1 a2 b2 a3]" {
+		t.Errorf("ordem = %v, esperado [a1 b1 c1 a2 b2 a3]", ordem)
+	}
+}
+
+func TestProximoMenosEmProcessamento(t *testing.T) {
+	a := abrirFila(t, filepath.Join(t.TempDir(), "fila.json"))
+	enfileirar(t, a, "B", "b1", "b2")
+	enfileirar(t, a, "A", "a1", "a2")
+
+	proximo(t, a) // b1 continua em processamento
+	if r := proximo(t, a); r.ID != "a1" {
+		t.Fatalf("Proximo = %s, esperado a1", r.ID)
+	}
+	if err := a.Encerrar("a1", Concluido, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// B foi atendido há mais tempo, mas A não tem nada em processamento
+	if r := proximo(t, a); r.ID != "a2" {
+		t.Errorf("Proximo = %s, esperado a2", r.ID)
+	}
+}
+
+func TestPosicao(t *testing.T) {
+	a := abrirFila(t, filepath.Join(t.TempDir(), "fila.json"))
+	enfileirar(t, a, "A", "a1", "a2", "a3")
+	enfileirar(t, a, "B", "b1")
+	enfileirar(t, a, "C", "c1")
+
+	posicoes := func() string {
+		var p []string
+		for _, id := range []string{"a1", "a2", "a3", "b1", "c1", "x"} {
+			p = append(p, fmt.Sprintf("%s=%d", id, a.Posicao(id)))
+		}
+		return strings.Join(p, " ")
+	}
+
+	// Os trabalhos de A depois do primeiro esperam a rodada de B e C
+	if obtido, esperado := posicoes(), "a1=1 a2=4 a3=5 b1=2 c1=3 x=0"; obtido != esperado {
+		t.Errorf("posições = %s, esperado %s", obtido, esperado)
+	}
+
+	// A acabou de ser atendido, então B e C passam na frente de a2; a
+	// estimativa tem de bater com a ordem em que Proximo os entrega
+	proximo(t, a)
+	if obtido, esperado := posicoes(), "a1=0 a2=3 a3=4 b1=1 c1=2 x=0"; obtido != esperado {
+		t.Errorf("posições = %s, esperado %s", obtido, esperado)
+	}
+	var ordem []string
+	for range 4 {
+		r := proximo(t, a)
+		ordem = append(ordem, r.ID)
+		a.Encerrar(r.ID, Concluido, "")
+	}
+	if fmt.Sprint(ordem) != "[b1 c1 a2 a3]" {
+		t.Errorf("ordem = %v, esperado [b1 c1 a2 a3]", ordem)
+	}
+}
+
+func TestCancelar(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "fila.json")
+	a := abrirFila(t, caminho)
+	enfileirar(t, a, "A", "a1", "a2")
+	proximo(t, a)
+
+	casos := []struct {
+		nome     string
+		id, dono string
+		estado   Estado // estado devolvido por Cancelar
+		erro     bool
+		final    Estado // estado gravado depois de Cancelar
+	}{
+		{"de outro dono", "a2", "B", "", true, NaFila},
+		{"inexistente", "x", "A", "", true, ""},
+		{"aguardando", "a2", "A", NaFila, false, Cancelado},
+		{"já cancelado", "a2", "A", Cancelado, true, Cancelado},
+		{"em processamento", "a1", "A", Processando, false, Processando},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			estado, err := a.Cancelar(c.id, c.dono)
+			if estado != c.estado || (err != nil) != c.erro {
+				t.Errorf("Cancelar = %q, %v; esperado %q (erro: %v)", estado, err, c.estado, c.erro)
+			}
+			if c.final != "" {
+				if r := registro(t, caminho, c.id); r.Estado != c.final {
+					t.Errorf("estado gravado = %s, esperado %s", r.Estado, c.final)
+				}
+			}
+		})
+	}
+
+	// O cancelado sai da fila; o em processamento é encerrado por quem o executa
+	if p := a.Posicao("a2"); p != 0 {
+		t.Errorf("posição do cancelado = %d, esperado 0", p)
+	}
+	if err := a.Encerrar("a1", Cancelado, ""); err != nil {
+		t.Fatal(err)
+	}
+	if estado, err := a.Cancelar("a1", "A"); estado != Cancelado || err == nil {
+		t.Errorf("Cancelar de encerrado = %q, %v; esperado %q com erro", estado, err, Cancelado)
+	}
+	if p := a.Pendentes(); len(p) != 0 {
+		t.Errorf("%d trabalhos pendentes, esperado 0", len(p))
+	}
+}
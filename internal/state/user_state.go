@@ -2,15 +2,12 @@ package state
 
 import "sync"
 
+// DatabaseType guarda a chave do conversor escolhido (ver conversao.Chave)
 type DatabaseType string
 
-const (
-	Eclipse DatabaseType = "eclipse"
-	Atlas   DatabaseType = "atlas"
-)
-
 type UserState struct {
 	DatabaseChoice DatabaseType
+	Simulacao      bool
 }
 
 var (
@@ -40,6 +37,28 @@ func GetUserDatabaseChoice(chatID int64) DatabaseType {
 	return ""
 }
 
+// SetUserSimulacao liga ou desliga o modo de simulação do usuário
+func SetUserSimulacao(chatID int64, ativo bool) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if _, exists := userStates[chatID]; !exists {
+		userStates[chatID] = &UserState{}
+	}
+	userStates[chatID].Simulacao = ativo
+}
+
+// GetUserSimulacao informa se o usuário está no modo de simulação
+func GetUserSimulacao(chatID int64) bool {
+	stateMutex.RLock()
+	defer stateMutex.RUnlock()
+
+	if state, exists := userStates[chatID]; exists {
+		return state.Simulacao
+	}
+	return false
+}
+
 // ClearUserState limpa o estado do usuário
 func ClearUserState(chatID int64) {
 	stateMutex.Lock()
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -13,6 +20,8 @@ import (
 
 	"conversao-db/internal/conversao"
 	"conversao-db/internal/db"
+	"conversao-db/internal/dump"
+	"conversao-db/internal/fila"
 	"conversao-db/internal/state"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -20,179 +29,608 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// Canais de entrada de um trabalho de conversão
+const (
+	canalTelegram = "telegram"
+	canalHTTP     = "http"
+)
+
 // ConversionJob representa um trabalho de conversão na fila
 type ConversionJob struct {
-	ChatID      int64
-	FileID      string
-	FileName    string
-	DownloadURL string
+	ID        string // identificador do trabalho na fila e na API HTTP
+	Canal     string // canalTelegram ou canalHTTP
+	ChatID    int64
+	FileID    string // arquivo no Telegram; o link de download é obtido ao processar
+	FileName  string
+	FileSize  int64  // tamanho informado pelo Telegram; 0 se desconhecido
+	InputFile string // arquivo já salvo localmente, quando não há download
+	Membro    string // dump escolhido dentro de um pacote zip ou tar
+	Conversor string // chave do conversor escolhido; vazia para detectar
+	Simular   bool   // só simula a conversão, sem tocar no MySQL
+	Cliente   string // endereço de quem enviou o trabalho pela API HTTP
+}
+
+// Dono identifica quem enviou o trabalho, para revezar a fila entre usuários
+func (job ConversionJob) Dono() string {
+	if job.Canal == canalHTTP {
+		return canalHTTP + ":" + job.Cliente
+	}
+	return fmt.Sprintf("%s:%d", canalTelegram, job.ChatID)
 }
 
-// WorkQueue gerencia a fila de trabalhos
+// simulacoesPendentes guarda, por chat, o último trabalho simulado que
+// aguarda confirmação do usuário para ser executado de fato
+var simulacoesPendentes = struct {
+	sync.Mutex
+	jobs map[int64]ConversionJob
+}{jobs: make(map[int64]ConversionJob)}
+
+// dumpsPendentes guarda, por chat, o último trabalho cujo arquivo tem vários
+// dumps, com os nomes deles, até o usuário escolher qual converter
+var dumpsPendentes = struct {
+	sync.Mutex
+	jobs  map[int64]ConversionJob
+	nomes map[int64][]string
+}{jobs: make(map[int64]ConversionJob), nomes: make(map[int64][]string)}
+
+// WorkQueue gerencia a fila de trabalhos. Os trabalhos ficam gravados em
+// disco (ver fila.Armazem) e são retomados quando o bot reinicia.
 type WorkQueue struct {
-	jobs    chan ConversionJob
-	working sync.WaitGroup
+	armazem    *fila.Armazem
+	numWorkers int
+	working    sync.WaitGroup
+
+	// execucoes guarda como interromper cada trabalho em processamento
+	mu        sync.Mutex
+	execucoes map[string]context.CancelFunc
 }
 
-// NewWorkQueue cria uma nova fila de trabalhos
-func NewWorkQueue(numWorkers int) *WorkQueue {
-	return &WorkQueue{
-		jobs: make(chan ConversionJob, 100), // buffer para até 100 trabalhos
+// NewWorkQueue cria uma nova fila de trabalhos sobre o armazém informado,
+// atendida por numWorkers trabalhos simultâneos
+func NewWorkQueue(numWorkers int, armazem *fila.Armazem) *WorkQueue {
+	if numWorkers < 1 {
+		numWorkers = 1
 	}
+	return &WorkQueue{armazem: armazem, numWorkers: numWorkers, execucoes: make(map[string]context.CancelFunc)}
 }
 
-// AddJob adiciona um novo trabalho à fila
-func (wq *WorkQueue) AddJob(job ConversionJob) {
-	wq.jobs <- job
+// AddJob grava um novo trabalho na fila e devolve a posição estimada dele
+// (0 se já começou a ser processado). Não bloqueia: a fila não tem limite
+// de tamanho.
+func (wq *WorkQueue) AddJob(job ConversionJob) (int, error) {
+	if job.ID == "" {
+		id, err := novoIDJob()
+		if err != nil {
+			return 0, err
+		}
+		job.ID = id
+	}
+	if err := wq.armazem.Enfileirar(job.ID, job.Dono(), job); err != nil {
+		return 0, err
+	}
+	return wq.armazem.Posicao(job.ID), nil
 }
 
-// ProcessJobs inicia o processamento de trabalhos
-func (wq *WorkQueue) ProcessJobs(bot *tgbotapi.BotAPI, dsn string) {
-	wq.working.Add(1)
-	go func() {
-		defer wq.working.Done()
-		for job := range wq.jobs {
-			// Notifica o usuário que seu arquivo está na fila
-			bot.Send(tgbotapi.NewMessage(job.ChatID, "Seu arquivo está na fila de processamento. Aguarde..."))
+// Posicao devolve a posição estimada do trabalho na fila, ou 0 se ele não
+// está aguardando
+func (wq *WorkQueue) Posicao(id string) int {
+	return wq.armazem.Posicao(id)
+}
 
-			// Processa o trabalho
-			processConversionJob(bot, job, dsn)
+// Cancelar cancela um trabalho de dono: tira da fila se ainda aguarda ou
+// interrompe o processamento. Devolve o estado em que o trabalho estava.
+func (wq *WorkQueue) Cancelar(id, dono string) (fila.Estado, error) {
+	estado, err := wq.armazem.Cancelar(id, dono)
+	if err != nil {
+		return estado, err
+	}
+	if estado == fila.Processando {
+		wq.mu.Lock()
+		cancel := wq.execucoes[id]
+		wq.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	}
+	return estado, nil
+}
+
+// Listar devolve os trabalhos de dono, do mais antigo ao mais novo
+func (wq *WorkQueue) Listar(dono string) []fila.Registro {
+	return wq.armazem.Listar(dono)
+}
 
-			// Pequena pausa entre processamentos
-			time.Sleep(1 * time.Second)
+// Pendentes devolve os trabalhos ainda não encerrados, na ordem da fila
+func (wq *WorkQueue) Pendentes() []ConversionJob {
+	var jobs []ConversionJob
+	for _, r := range wq.armazem.Pendentes() {
+		var job ConversionJob
+		if err := json.Unmarshal(r.Dados, &job); err != nil {
+			log.Printf("Trabalho %s ilegível na fila: %v", r.ID, err)
+			continue
 		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// ProcessJobs inicia os workers. notificador devolve, para cada trabalho,
+// quem recebe as mensagens e o resultado.
+func (wq *WorkQueue) ProcessJobs(notificador func(ConversionJob) Notificador, dsn string) {
+	for i := 0; i < wq.numWorkers; i++ {
+		wq.working.Add(1)
+		go func() {
+			defer wq.working.Done()
+			for {
+				wq.processarProximo(notificador, dsn)
+			}
+		}()
+	}
+}
+
+// processarProximo espera o próximo trabalho da fila, processa e registra
+// o estado final
+func (wq *WorkQueue) processarProximo(notificador func(ConversionJob) Notificador, dsn string) {
+	reg, errFila := wq.armazem.Proximo()
+
+	var job ConversionJob
+	if err := json.Unmarshal(reg.Dados, &job); err != nil {
+		log.Printf("Trabalho %s ilegível na fila: %v", reg.ID, err)
+		wq.encerrar(reg.ID, fila.Falhou, "trabalho ilegível")
+		return
+	}
+	if errFila != nil {
+		// O trabalho já saiu da fila na memória, mas o arquivo não foi
+		// gravado. Sem garantia de retomá-lo, ele é encerrado como falho e o
+		// usuário é avisado para enviá-lo de novo.
+		log.Printf("Erro ao atualizar a fila: %v", errFila)
+		wq.encerrar(job.ID, fila.Falhou, "erro ao atualizar a fila")
+		notificador(job).Falha(fmt.Sprintf("Erro ao iniciar a conversão do arquivo %s. Envie o arquivo novamente.", job.FileName))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wq.mu.Lock()
+	wq.execucoes[job.ID] = cancel
+	wq.mu.Unlock()
+	defer func() {
+		wq.mu.Lock()
+		delete(wq.execucoes, job.ID)
+		wq.mu.Unlock()
+		cancel()
 	}()
+
+	n := &notificadorFila{Notificador: notificador(job)}
+
+	// Processa o trabalho
+	executarJob(ctx, n, job, dsn)
+
+	switch {
+	case ctx.Err() != nil:
+		wq.encerrar(job.ID, fila.Cancelado, "")
+	case n.falha != "":
+		wq.encerrar(job.ID, fila.Falhou, n.falha)
+	default:
+		wq.encerrar(job.ID, fila.Concluido, "")
+	}
+}
+
+func (wq *WorkQueue) encerrar(id string, estado fila.Estado, erro string) {
+	if err := wq.armazem.Encerrar(id, estado, erro); err != nil {
+		log.Printf("Erro ao atualizar a fila: %v", err)
+	}
 }
 
-// processConversionJob processa um trabalho de conversão individual
-func processConversionJob(bot *tgbotapi.BotAPI, job ConversionJob, dsn string) {
-	// Obtém a escolha do usuário
-	dbChoice := state.GetUserDatabaseChoice(job.ChatID)
+// semaforo limita quantos trabalhos usam um recurso ao mesmo tempo
+type semaforo chan struct{}
 
-	// Notifica início do processamento
-	bot.Send(tgbotapi.NewMessage(job.ChatID, fmt.Sprintf("Iniciando processamento do seu arquivo para o banco %s...", dbChoice)))
+func novoSemaforo(limite int) semaforo {
+	if limite < 1 {
+		limite = 1
+	}
+	return make(semaforo, limite)
+}
 
-	inputFile := job.FileName
-	if !strings.HasSuffix(inputFile, ".sql") {
-		inputFile = "entrada.sql"
+// ocupar espera uma vaga, desistindo se ctx for cancelado
+func (s semaforo) ocupar(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	err := conversao.DownloadFile(job.DownloadURL, inputFile)
-	if err != nil {
-		bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro ao salvar o arquivo."))
+func (s semaforo) liberar() { <-s }
+
+// Limites globais, valendo para todos os workers juntos; definidos em init
+// por LIMITE_MYSQL e LIMITE_DISCO
+var (
+	// limiteMySQL é o número de cargas simultâneas no MySQL
+	limiteMySQL semaforo
+	// limiteDisco é o número de trabalhos gravando arquivos grandes (download
+	// e arquivo convertido) ao mesmo tempo
+	limiteDisco semaforo
+)
+
+// idadeBancoOrfao é a idade a partir da qual um banco temporário é tido
+// como abandonado. Fica bem acima da duração de qualquer conversão, para não
+// apagar o banco de um trabalho em andamento em outra instância.
+const idadeBancoOrfao = 6 * time.Hour
+
+// Limites de cada arquivo recebido; definidos em init por LIMITE_ARQUIVO_MB,
+// LIMITE_DESCOMPACTADO_MB e TEMPO_DOWNLOAD_MIN
+var (
+	tamanhoMaximoArquivo int64
+	// tamanhoMaximoDescompactado vale para o dump extraído de um arquivo
+	// compactado, que pode ser muito maior que o arquivo recebido
+	tamanhoMaximoDescompactado int64
+	tempoMaximoDownload        time.Duration
+)
+
+// inteiroDoAmbiente lê uma variável de ambiente numérica, com valor padrão
+// quando ausente ou inválida
+func inteiroDoAmbiente(nome string, padrao int) int {
+	v := os.Getenv(nome)
+	if v == "" {
+		return padrao
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Printf("Valor inválido para %s (%q); usando %d", nome, v, padrao)
+		return padrao
+	}
+	return n
+}
+
+// novoIDJob gera um identificador aleatório para um trabalho
+func novoIDJob() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erro ao gerar id do trabalho: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// executarJob processa um trabalho isolando falhas: um panic é registrado no
+// log, o usuário é avisado e o worker segue para o próximo trabalho. Os
+// arquivos temporários são removidos pelos defers de processConversionJob,
+// que rodam também durante o panic.
+func executarJob(ctx context.Context, n Notificador, job ConversionJob, dsn string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic ao processar o arquivo %s (%s %d, trabalho %s): %v\n%s", job.FileName, job.Canal, job.ChatID, job.ID, r, debug.Stack())
+			n.Falha(fmt.Sprintf("Falha interna ao converter o arquivo %s: %v\nA conversão foi cancelada. Verifique o arquivo e tente novamente.", job.FileName, r))
+		}
+	}()
+
+	// Avisa o usuário que o trabalho saiu da fila
+	n.Iniciado()
+	processConversionJob(ctx, n, job, dsn)
+}
+
+// falhar encerra o trabalho com o erro informado ou, se o erro veio do
+// cancelamento de ctx, avisa que a conversão foi cancelada
+func falhar(ctx context.Context, n Notificador, texto string) {
+	if ctx.Err() != nil {
+		n.Falha("Conversão cancelada.")
 		return
 	}
+	n.Falha(texto)
+}
 
-	var dbExport interface{}
-	var errProcess error
+// escolherConversor detecta o formato do dump e decide qual conversor usar.
+// Quando o formato detectado difere da escolha do usuário, o detectado é
+// usado e o usuário é avisado; sem detecção, vale a escolha do usuário.
+func escolherConversor(ctx context.Context, n Notificador, escolhido conversao.Converter, entrada conversao.Entrada) conversao.Converter {
+	detectado, err := conversao.DetectarFormato(ctx, entrada)
+	if ctx.Err() != nil {
+		falhar(ctx, n, "")
+		return nil
+	}
+	if err != nil {
+		log.Printf("Erro ao detectar o formato do arquivo %s: %v", entrada.Arquivo, err)
+	}
 
-	switch dbChoice {
-	case state.Atlas:
-		// Processar no formato Atlas
-		dbExport, errProcess = conversao.ProcessarArquivoSQLFinal(inputFile)
-		if errProcess != nil {
-			bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro ao processar o arquivo: "+errProcess.Error()))
-			return
+	switch {
+	case detectado == nil && escolhido == nil:
+		n.Falha("Não foi possível identificar o formato do arquivo. Escolha o formato de origem e envie o arquivo novamente.")
+		return nil
+	case detectado == nil:
+		return escolhido
+	case escolhido == nil:
+		n.Mensagem(fmt.Sprintf("Formato detectado: %s.", detectado.Nome()))
+	case conversao.ChaveDe(detectado) != conversao.ChaveDe(escolhido):
+		n.Mensagem(fmt.Sprintf(
+			"Atenção: você escolheu %s, mas o arquivo parece ser do painel %s. A conversão será feita como %s.",
+			escolhido.Nome(), detectado.Nome(), detectado.Nome()))
+	}
+	return detectado
+}
+
+// escolherDump decide qual dump do arquivo converter. Num pacote com vários
+// dumps vale o escolhido pelo usuário ou, sem escolha, o único em formato
+// reconhecido; se não houver como decidir, o usuário é perguntado e o
+// trabalho termina. Devolve também o tamanho descompactado do dump (0 se
+// desconhecido).
+func escolherDump(ctx context.Context, n Notificador, job ConversionJob, arquivo string) (conversao.Entrada, int64, bool) {
+	base := conversao.Entrada{Arquivo: arquivo, LimiteDescompactado: tamanhoMaximoDescompactado}
+	entradaDe := func(membro string) conversao.Entrada {
+		e := base
+		e.Membro = membro
+		return e
+	}
+
+	dumps, err := conversao.ListarDumps(base)
+	if err != nil {
+		n.Falha("Erro ao abrir o arquivo: " + err.Error())
+		return conversao.Entrada{}, 0, false
+	}
+
+	switch {
+	case len(dumps) == 0:
+		n.Falha("Nenhum dump .sql encontrado no arquivo enviado.")
+		return conversao.Entrada{}, 0, false
+	case len(dumps) == 1:
+		return entradaDe(dumps[0].Nome), dumps[0].Tamanho, true
+	case job.Membro != "":
+		for _, d := range dumps {
+			if d.Nome == job.Membro {
+				return entradaDe(d.Nome), d.Tamanho, true
+			}
 		}
-		err = db.EnviarParaMySQLFinal(dbExport.(*conversao.DatabaseFinal), dsn)
-	case state.Eclipse:
-		// Processar no formato Eclipse (original)
-		dbExport, errProcess = conversao.ProcessarArquivoSQL(inputFile)
-		if errProcess != nil {
-			bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro ao processar o arquivo: "+errProcess.Error()))
-			return
+		n.Falha(fmt.Sprintf("O dump %s não foi encontrado no arquivo.", job.Membro))
+		return conversao.Entrada{}, 0, false
+	}
+
+	var reconhecidos []conversao.Dump
+	nomes := make([]string, len(dumps))
+	for i, d := range dumps {
+		nomes[i] = d.Nome
+		conv, err := conversao.DetectarFormato(ctx, entradaDe(d.Nome))
+		if ctx.Err() != nil {
+			falhar(ctx, n, "")
+			return conversao.Entrada{}, 0, false
 		}
-		err = db.EnviarParaMySQL(dbExport.(*conversao.DatabaseExport), dsn)
-	default:
-		bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro: tipo de banco de dados não selecionado."))
+		if err != nil {
+			log.Printf("Erro ao detectar o formato de %s em %s: %v", d.Nome, arquivo, err)
+			continue
+		}
+		if conv != nil {
+			reconhecidos = append(reconhecidos, d)
+		}
+	}
+	if len(reconhecidos) == 1 {
+		d := reconhecidos[0]
+		n.Mensagem(fmt.Sprintf("O arquivo tem %d dumps SQL; será convertido %s, o único em formato reconhecido.", len(dumps), d.Nome))
+		return entradaDe(d.Nome), d.Tamanho, true
+	}
+
+	n.EscolherDump(job, nomes)
+	return conversao.Entrada{}, 0, false
+}
+
+// simularConversao lê e transforma o dump sem tocar no MySQL e entrega o
+// resumo a quem pediu a conversão
+func simularConversao(ctx context.Context, n Notificador, job ConversionJob, conv conversao.Converter, entrada conversao.Entrada) {
+	sim, err := conversao.Simular(ctx, conv, entrada)
+	if err != nil {
+		falhar(ctx, n, "Erro ao processar o arquivo: "+err.Error())
 		return
 	}
+	n.Simulacao(job, sim.Texto())
+}
 
+// processConversionJob processa um trabalho de conversão individual.
+// Cancelar ctx interrompe o download, a leitura e a carga no MySQL.
+func processConversionJob(ctx context.Context, n Notificador, job ConversionJob, dsn string) {
+	// Obtém o conversor escolhido pelo usuário (pode estar vazio: o formato
+	// é detectado pelo conteúdo do dump)
+	escolhido, _ := conversao.ObterPorChave(job.Conversor)
+
+	// Cada trabalho usa um diretório próprio, removido ao final mesmo em caso
+	// de erro, para que nomes de arquivo de trabalhos diferentes não colidam
+	area, err := criarAreaTrabalho(job.ID)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro ao enviar para o MySQL: "+err.Error()))
+		n.Falha("Erro ao preparar o processamento: " + err.Error())
 		return
 	}
+	defer os.RemoveAll(area)
 
-	// Gerar backup do banco de dados usando mysqldump
-	backupDir := "backups"
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro ao criar diretório de backup: "+err.Error()))
+	nome := nomeArquivoSeguro(job.FileName)
+	inputFile := job.InputFile
+	if job.FileID != "" {
+		inputFile = filepath.Join(area, nome)
+	} else {
+		// Upload da API: o arquivo já está em disco e também é removido ao final
+		defer os.Remove(inputFile)
+	}
+
+	if job.FileID != "" {
+		n.Progresso("Baixando o arquivo", "")
+		if err := baixarArquivo(ctx, job, inputFile); err != nil {
+			falhar(ctx, n, mensagemErroDownload(err))
+			return
+		}
+	}
+
+	entrada, tamanho, ok := escolherDump(ctx, n, job, inputFile)
+	if !ok {
+		return
+	}
+
+	conv := escolherConversor(ctx, n, escolhido, entrada)
+	if conv == nil {
+		return
+	}
+
+	if job.Simular {
+		simularConversao(ctx, n, job, conv, entrada)
 		return
 	}
 
-	inputFileBase := filepath.Base(inputFile)
-	backupFileName := strings.TrimSuffix(inputFileBase, ".sql") + "-convertido.sql"
-	backupFile := filepath.Join(backupDir, backupFileName)
-
-	// Comando mysqldump
-	cmd := exec.Command("mysqldump",
-		"--host="+dbHost,
-		"--port="+dbPort,
-		"--protocol=tcp",
-		"--user="+dbUser,
-		"--password="+dbPass,
-		"--default-character-set=utf8mb4",
-		"--no-create-db",
-		dbName,
-		"--result-file="+backupFile,
-		"--single-transaction")
-
-	output, err := cmd.CombinedOutput()
+	// Notifica início do processamento
+	n.Mensagem(fmt.Sprintf("Iniciando processamento do seu arquivo para o banco %s...", conv.Nome()))
+
+	etapaLeitura := fmt.Sprintf("Lendo o dump (%s)", conv.Nome())
+	n.Progresso(etapaLeitura, "")
+	acomp := &conversao.Acompanhamento{
+		Leitura: func(registros, bytes int64) {
+			n.Progresso(etapaLeitura, fmt.Sprintf("%d registro(s) lido(s) (%s)", registros, porcentagem(bytes, tamanho)))
+		},
+		Carga: func(linhas, total int64) {
+			n.Progresso("Carregando no MySQL", fmt.Sprintf("%s (%d de %d linhas)", porcentagem(linhas, total), linhas, total))
+		},
+	}
+
+	dados, err := conv.Ler(ctx, entrada, acomp)
+	if err == nil {
+		dados, err = conv.Transformar(dados)
+	}
 	if err != nil {
-		errMsg := fmt.Sprintf("Erro ao gerar backup: %v\nSaída: %s", err, string(output))
-		bot.Send(tgbotapi.NewMessage(job.ChatID, errMsg))
+		falhar(ctx, n, "Erro ao processar o arquivo: "+err.Error())
+		return
+	}
+	if resumo := dados.Diagnostico().Resumo(); resumo != "" {
+		n.Mensagem(resumo)
+	}
+
+	// Gerar o arquivo convertido
+	backupFile := filepath.Join(area, conversao.NomeBase(nome)+"-convertido.sql")
+
+	if err := gerarResultado(ctx, n, conv, dados, dsn, backupFile, acomp); err != nil {
+		falhar(ctx, n, "Erro ao gerar backup: "+err.Error())
 		return
 	}
 
 	// Verifica se o arquivo foi criado
 	if _, err := os.Stat(backupFile); err != nil {
-		bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro: arquivo de backup não foi criado"))
+		n.Falha("Erro: arquivo de backup não foi criado")
 		return
 	}
 
-	// Informa que o backup foi gerado e será enviado
-	bot.Send(tgbotapi.NewMessage(job.ChatID, "Backup gerado com sucesso! Enviando arquivo..."))
+	if err := n.Resultado(backupFile); err != nil {
+		log.Printf("Erro ao entregar o arquivo %s: %v", backupFile, err)
+	}
+}
+
+// porcentagem formata feito/total como porcentagem inteira
+func porcentagem(feito, total int64) string {
+	if total <= 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%d%%", feito*100/total)
+}
 
-	// Prepara o documento para envio
-	doc := tgbotapi.NewDocument(job.ChatID, tgbotapi.FilePath(backupFile))
-	doc.Caption = "Backup do banco de dados"
+// linkArquivoTelegram devolve o endereço de download de um arquivo do
+// Telegram; nil enquanto o bot não está ativo. O endereço leva o token do bot
+// e expira, por isso só o FileID fica gravado na fila e o link é obtido
+// quando o trabalho começa.
+var linkArquivoTelegram func(fileID string) (string, error)
 
-	// Tenta enviar o arquivo
-	_, err = bot.Send(doc)
+// baixarArquivo faz o download do arquivo do trabalho respeitando o limite
+// global de disco e os limites de cada arquivo
+func baixarArquivo(ctx context.Context, job ConversionJob, arquivo string) error {
+	if linkArquivoTelegram == nil {
+		return errors.New("bot do Telegram não está ativo")
+	}
+	url, err := linkArquivoTelegram(job.FileID)
 	if err != nil {
-		errMsg := fmt.Sprintf("Erro ao enviar o backup: %v", err)
-		bot.Send(tgbotapi.NewMessage(job.ChatID, errMsg))
-		return
+		return fmt.Errorf("erro ao obter o arquivo no Telegram: %v", err)
 	}
 
-	// Confirma o envio
-	bot.Send(tgbotapi.NewMessage(job.ChatID, "Backup enviado com sucesso!"))
+	if err := limiteDisco.ocupar(ctx); err != nil {
+		return err
+	}
+	defer limiteDisco.liberar()
+	return conversao.DownloadFile(ctx, url, arquivo, conversao.OpcoesDownload{
+		TamanhoMaximo:   tamanhoMaximoArquivo,
+		TamanhoEsperado: job.FileSize,
+		Timeout:         tempoMaximoDownload,
+	})
+}
 
-	// Remove o arquivo de backup local após envio bem sucedido
-	err = os.Remove(backupFile)
+// mensagemErroDownload descreve para o usuário uma falha no download
+func mensagemErroDownload(err error) string {
+	switch {
+	case errors.Is(err, conversao.ErrArquivoGrande):
+		return fmt.Sprintf("O arquivo passa do limite de %d MB.", tamanhoMaximoArquivo>>20)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "O download do arquivo demorou demais. Tente novamente."
+	default:
+		log.Printf("Erro no download: %v", err)
+		return "Erro ao salvar o arquivo."
+	}
+}
+
+// gerarResultado grava o arquivo convertido, direto dos dados no modo
+// offline ou passando pelo MySQL, respeitando os limites globais
+func gerarResultado(ctx context.Context, n Notificador, conv conversao.Converter, dados conversao.Dados, dsn, arquivo string, acomp *conversao.Acompanhamento) error {
+	if err := limiteDisco.ocupar(ctx); err != nil {
+		return err
+	}
+	defer limiteDisco.liberar()
+
+	if modoOffline {
+		n.Progresso("Gerando o arquivo convertido", "")
+		return gerarArquivoOffline(dados, arquivo)
+	}
+
+	if err := limiteMySQL.ocupar(ctx); err != nil {
+		return err
+	}
+	defer limiteMySQL.liberar()
+	return converterViaMySQL(ctx, n, conv, dados, dsn, arquivo, acomp)
+}
+
+// gerarArquivoOffline grava o SQL do painel de destino direto dos dados
+// convertidos, sem passar por um servidor MySQL
+func gerarArquivoOffline(dados conversao.Dados, arquivo string) error {
+	tabelas, err := conversao.TabelasDe(dados)
 	if err != nil {
-		errMsg := fmt.Sprintf("Aviso: não foi possível remover o arquivo de backup local: %v", err)
-		bot.Send(tgbotapi.NewMessage(job.ChatID, errMsg))
+		return err
 	}
+	return dump.EscreverArquivo(arquivo, tabelas)
+}
 
-	// Limpar as tabelas para deixar o banco pronto para a próxima importação
-	dbConn, err := db.OpenDB(dsn)
+// converterViaMySQL carrega os dados num banco temporário próprio do
+// trabalho, removido ao final para que conversões diferentes não misturem
+// dados, e grava o dump desse banco no arquivo
+func converterViaMySQL(ctx context.Context, n Notificador, conv conversao.Converter, dados conversao.Dados, dsn, arquivo string, acomp *conversao.Acompanhamento) error {
+	bancoJob, dsnJob, err := db.CriarBancoRascunho(dsn)
 	if err != nil {
-		errMsg := fmt.Sprintf("Erro ao conectar ao banco para limpeza: %v", err)
-		bot.Send(tgbotapi.NewMessage(job.ChatID, errMsg))
-	} else {
-		err = db.LimparTabelas(dbConn)
-		if err != nil {
-			errMsg := fmt.Sprintf("Erro ao limpar tabelas: %v", err)
-			bot.Send(tgbotapi.NewMessage(job.ChatID, errMsg))
-		} else {
-			bot.Send(tgbotapi.NewMessage(job.ChatID, "Tabelas limpas com sucesso! Banco pronto para próxima importação."))
+		return fmt.Errorf("erro ao preparar o banco temporário: %v", err)
+	}
+	defer func() {
+		if err := db.RemoverBancoRascunho(dsn, bancoJob); err != nil {
+			log.Printf("Erro ao remover o banco temporário %s: %v", bancoJob, err)
 		}
-		dbConn.Close()
+	}()
+
+	if err := conv.Carregar(ctx, dados, dsnJob, acomp); err != nil {
+		return fmt.Errorf("erro ao enviar para o MySQL: %v", err)
 	}
+	n.Progresso("Gerando o arquivo convertido", "")
+	return gerarBackup(ctx, dsnJob, arquivo)
+}
 
-	// Remove o arquivo SQL original após processamento
-	os.Remove(inputFile)
+// gerarBackup grava em arquivo o dump SQL do banco da DSN
+func gerarBackup(ctx context.Context, dsn, arquivo string) error {
+	conn, err := db.OpenDB(dsn)
+	if err != nil {
+		return fmt.Errorf("erro ao conectar ao banco: %v", err)
+	}
+	defer conn.Close()
+
+	out, err := os.Create(arquivo)
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo: %v", err)
+	}
+	if err := dump.EscreverBanco(ctx, conn, out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
 func checkLock() bool {
@@ -245,12 +683,17 @@ var (
 	dbUser string
 	dbPass string
 	dbName string
+
+	// modoOffline gera o SQL convertido sem servidor MySQL. Fica ativo com
+	// MODO_OFFLINE=1 ou quando as variáveis do banco não estão definidas.
+	modoOffline bool
 )
 
 func init() {
-	// Carrega o arquivo .env
+	// Carrega o arquivo .env, se existir; as variáveis também podem vir do
+	// ambiente
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Erro ao carregar arquivo .env")
+		log.Printf("Arquivo .env não carregado (%v); usando as variáveis de ambiente", err)
 	}
 
 	// Carrega as variáveis de ambiente
@@ -260,9 +703,32 @@ func init() {
 	dbPass = os.Getenv("DB_PASS")
 	dbName = os.Getenv("DB_NAME")
 
-	// Verifica se todas as variáveis necessárias estão definidas
-	if dbHost == "" || dbPort == "" || dbUser == "" || dbPass == "" || dbName == "" {
-		log.Fatal("Variáveis de ambiente necessárias não encontradas no arquivo .env")
+	modoOffline = os.Getenv("MODO_OFFLINE") == "1"
+
+	limiteMySQL = novoSemaforo(inteiroDoAmbiente("LIMITE_MYSQL", 1))
+	limiteDisco = novoSemaforo(inteiroDoAmbiente("LIMITE_DISCO", 2))
+
+	limiteArquivoMB := inteiroDoAmbiente("LIMITE_ARQUIVO_MB", 200)
+	tamanhoMaximoArquivo = int64(limiteArquivoMB) << 20
+	tamanhoMaximoDescompactado = int64(inteiroDoAmbiente("LIMITE_DESCOMPACTADO_MB", 10*limiteArquivoMB)) << 20
+	tempoMaximoDownload = time.Duration(inteiroDoAmbiente("TEMPO_DOWNLOAD_MIN", 10)) * time.Minute
+
+	switch compactacaoResultado = os.Getenv("COMPACTAR_RESULTADO"); compactacaoResultado {
+	case "", compactacaoGzip, compactacaoZip:
+	default:
+		log.Printf("COMPACTAR_RESULTADO inválido (%s); use gzip ou zip. O resultado será enviado sem compactação", compactacaoResultado)
+		compactacaoResultado = ""
+	}
+
+	dirTrabalhos = os.Getenv("DIR_TRABALHO")
+	if dirTrabalhos == "" {
+		dirTrabalhos = filepath.Join(os.TempDir(), "conversao-db")
+	}
+
+	// Sem as variáveis do banco, a conversão roda no modo offline
+	if !modoOffline && (dbHost == "" || dbPort == "" || dbUser == "" || dbPass == "" || dbName == "") {
+		log.Println("Variáveis do banco não encontradas no arquivo .env; usando o modo offline")
+		modoOffline = true
 	}
 }
 
@@ -272,32 +738,118 @@ func main() {
 	}
 	defer removeLock()
 
-	// Carregar token do bot do arquivo .env
+	// Carregar token do bot do arquivo .env. Sem token, só a API HTTP
+	// atende (HTTP_ADDR precisa estar definido).
 	token := os.Getenv("BOT_TOKEN")
-	if token == "" {
+	enderecoHTTP := os.Getenv("HTTP_ADDR")
+	if token == "" && enderecoHTTP == "" {
 		log.Fatal("Token do bot não encontrado no arquivo .env")
 	}
+	// A API HTTP não atende sem autenticação
+	tokenHTTP := os.Getenv("HTTP_TOKEN")
+	if enderecoHTTP != "" && tokenHTTP == "" {
+		log.Fatal("HTTP_TOKEN não encontrado no arquivo .env; defina-o para usar a API HTTP")
+	}
 
 	// Monta a string de conexão usando as variáveis de ambiente
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, dbPort, dbName)
 
-	// Inicializa o bot
-	bot, err := tgbotapi.NewBotAPI(token)
+	// Remove bancos temporários deixados por execuções interrompidas. O
+	// servidor MySQL pode ser compartilhado com outras instâncias (o bot.lock
+	// só vale para esta máquina), então só saem os bancos antigos demais para
+	// pertencer a uma conversão em andamento.
+	if !modoOffline {
+		if removidos, err := db.RemoverBancosOrfaos(dsn, idadeBancoOrfao); err != nil {
+			log.Printf("Erro ao remover bancos temporários antigos: %v", err)
+		} else if removidos > 0 {
+			log.Printf("%d banco(s) temporário(s) antigo(s) removido(s)", removidos)
+		}
+	}
+
+	// Remove as áreas de trabalho deixadas por execuções interrompidas. Elas
+	// ficam no disco local, protegido pelo bot.lock, e nenhuma está em uso.
+	if removidas, err := removerAreasOrfas(); err != nil {
+		log.Printf("Erro ao remover diretórios de trabalho antigos: %v", err)
+	} else if removidas > 0 {
+		log.Printf("%d diretório(s) de trabalho antigo(s) removido(s)", removidas)
+	}
+
+	// Cria a fila de trabalho, compartilhada pelo bot e pela API HTTP
+	caminhoFila := os.Getenv("FILA_ARQUIVO")
+	if caminhoFila == "" {
+		caminhoFila = filepath.Join("dados", "fila.json")
+	}
+	armazem, err := fila.Abrir(caminhoFila)
 	if err != nil {
 		log.Fatal(err)
 	}
+	workQueue := NewWorkQueue(inteiroDoAmbiente("NUM_WORKERS", 1), armazem)
+	api := novoServidorAPI(workQueue, tokenHTTP)
+
+	// Trabalhos da API que ficaram na fila voltam a ser acompanhados
+	pendentes := workQueue.Pendentes()
+	for _, job := range pendentes {
+		if job.Canal == canalHTTP {
+			api.restaurar(job)
+		}
+	}
+	if len(pendentes) > 0 {
+		log.Printf("%d trabalho(s) pendente(s) retomado(s) da fila", len(pendentes))
+	}
+
+	// Resultados e uploads de execuções anteriores não são mais acompanhados
+	if removidos, err := api.removerArquivosOrfaos(); err != nil {
+		log.Printf("Erro ao remover arquivos antigos da API: %v", err)
+	} else if removidos > 0 {
+		log.Printf("%d arquivo(s) antigo(s) da API removido(s)", removidos)
+	}
+
+	var bot *tgbotapi.BotAPI
+	if token != "" {
+		// Inicializa o bot
+		bot, err = tgbotapi.NewBotAPI(token)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Bot autorizado na conta %s", bot.Self.UserName)
+		linkArquivoTelegram = bot.GetFileDirectURL
+	}
 
-	log.Printf("Bot autorizado na conta %s", bot.Self.UserName)
+	workQueue.ProcessJobs(func(job ConversionJob) Notificador {
+		if job.Canal == canalHTTP {
+			return api.notificador(job.ID)
+		}
+		if bot == nil {
+			return notificadorSemBot{job: job}
+		}
+		return &notificadorTelegram{bot: bot, chatID: job.ChatID, jobID: job.ID}
+	}, dsn)
+
+	if enderecoHTTP != "" {
+		go api.limparPeriodicamente(validadeResultado)
+		servidor := &http.Server{Addr: enderecoHTTP, Handler: api.Handler()}
+		if bot == nil {
+			log.Printf("API HTTP escutando em %s", enderecoHTTP)
+			log.Fatal(servidor.ListenAndServe())
+		}
+		go func() {
+			log.Printf("API HTTP escutando em %s", enderecoHTTP)
+			if err := servidor.ListenAndServe(); err != nil {
+				log.Printf("Erro na API HTTP: %v", err)
+			}
+		}()
+	}
+
+	atenderBot(bot, workQueue)
+}
 
+// atenderBot trata os comandos, botões e arquivos recebidos pelo bot
+func atenderBot(bot *tgbotapi.BotAPI, workQueue *WorkQueue) {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates := bot.GetUpdatesChan(u)
 
-	// Cria a fila de trabalho
-	workQueue := NewWorkQueue(1)
-	workQueue.ProcessJobs(bot, dsn)
-
 	// Canal para processar um arquivo por vez
 	for update := range updates {
 		if update.Message == nil && update.CallbackQuery == nil {
@@ -309,14 +861,24 @@ func main() {
 			callback := update.CallbackQuery
 			chatID := callback.Message.Chat.ID
 
-			switch callback.Data {
-			case "eclipse":
-				state.SetUserDatabaseChoice(chatID, state.Eclipse)
-				msg := "Você escolheu o banco Eclipse. Por favor, envie o arquivo SQL para conversão."
-				bot.Send(tgbotapi.NewMessage(chatID, msg))
-			case "atlas":
-				state.SetUserDatabaseChoice(chatID, state.Atlas)
-				msg := "Você escolheu o banco Atlas. Por favor, envie o arquivo SQL para conversão."
+			if callback.Data == "prosseguir" {
+				simulacoesPendentes.Lock()
+				job, ok := simulacoesPendentes.jobs[chatID]
+				delete(simulacoesPendentes.jobs, chatID)
+				simulacoesPendentes.Unlock()
+
+				if ok {
+					enfileirar(bot, workQueue, job)
+				} else {
+					bot.Send(tgbotapi.NewMessage(chatID, "Nenhuma simulação pendente. Envie o arquivo novamente."))
+				}
+			} else if i, ok := strings.CutPrefix(callback.Data, prefixoDump); ok {
+				escolherDumpPendente(bot, workQueue, chatID, i)
+			} else if id, ok := strings.CutPrefix(callback.Data, prefixoCancelar); ok {
+				cancelarTrabalho(bot, workQueue, chatID, id)
+			} else if conv, ok := conversao.ObterPorChave(callback.Data); ok {
+				state.SetUserDatabaseChoice(chatID, state.DatabaseType(callback.Data))
+				msg := fmt.Sprintf("Você escolheu o banco %s. Por favor, envie o arquivo SQL para conversão.", conv.Nome())
 				bot.Send(tgbotapi.NewMessage(chatID, msg))
 			}
 
@@ -331,18 +893,55 @@ func main() {
 			continue
 		}
 
+		// Comando /status: lista as conversões do chat
+		if msg.Command() == "status" {
+			enviarStatus(bot, workQueue, msg.Chat.ID, "")
+			continue
+		}
+
+		// Comando /cancel: cancela a conversão informada ou, se só houver
+		// uma pendente, essa; com várias, pede para escolher
+		if msg.Command() == "cancel" || msg.Command() == "cancelar" {
+			if id := strings.TrimSpace(msg.CommandArguments()); id != "" {
+				cancelarTrabalho(bot, workQueue, msg.Chat.ID, id)
+				continue
+			}
+			pendentes := trabalhosPendentes(workQueue, msg.Chat.ID)
+			switch len(pendentes) {
+			case 0:
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "Nenhuma conversão para cancelar."))
+			case 1:
+				cancelarTrabalho(bot, workQueue, msg.Chat.ID, pendentes[0].ID)
+			default:
+				enviarStatus(bot, workQueue, msg.Chat.ID, "Escolha qual conversão cancelar.")
+			}
+			continue
+		}
+
+		// Comando /simular: alterna o modo de simulação
+		if msg.Command() == "simular" {
+			ativo := !state.GetUserSimulacao(msg.Chat.ID)
+			state.SetUserSimulacao(msg.Chat.ID, ativo)
+			if ativo {
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID,
+					"Modo de simulação ativado. Os próximos arquivos serão apenas analisados, sem alterar o MySQL. Use /simular novamente para desativar."))
+			} else {
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "Modo de simulação desativado."))
+			}
+			continue
+		}
+
 		// Comando /start
 		if msg.Command() == "start" {
-			// Limpa estado anterior do usuário
-			state.ClearUserState(msg.Chat.ID)
+			// Limpa a escolha anterior do usuário (o modo de simulação é mantido)
+			state.SetUserDatabaseChoice(msg.Chat.ID, "")
 
-			// Cria teclado inline com os botões
-			keyboard := tgbotapi.NewInlineKeyboardMarkup(
-				tgbotapi.NewInlineKeyboardRow(
-					tgbotapi.NewInlineKeyboardButtonData("Eclipse", "eclipse"),
-					tgbotapi.NewInlineKeyboardButtonData("Atlas", "atlas"),
-				),
-			)
+			// Cria teclado inline com um botão por conversor registrado
+			var botoes []tgbotapi.InlineKeyboardButton
+			for _, conv := range conversao.Conversores() {
+				botoes = append(botoes, tgbotapi.NewInlineKeyboardButtonData(conv.Nome(), conversao.ChaveDe(conv)))
+			}
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(botoes...))
 
 			reply := tgbotapi.NewMessage(msg.Chat.ID,
 				"Bem-vindo ao Conversor de Banco de Dados!\n\n"+
@@ -354,31 +953,183 @@ func main() {
 
 		// Verifica se é um arquivo
 		if msg.Document != nil {
-			// Verifica se o usuário já escolheu o tipo de banco
-			if state.GetUserDatabaseChoice(msg.Chat.ID) == "" {
-				bot.Send(tgbotapi.NewMessage(msg.Chat.ID,
-					"Por favor, use o comando /start primeiro para escolher o tipo de banco de dados."))
-				continue
-			}
+			// Sem escolha do usuário, o formato é detectado pelo conteúdo do dump
 
 			// Obtém informações do arquivo
 			fileID := msg.Document.FileID
 			fileName := msg.Document.FileName
+			fileSize := int64(msg.Document.FileSize)
 
-			// Obtém URL do arquivo
-			file, err := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
-			if err != nil {
-				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "Erro ao obter o arquivo."))
+			if fileSize > tamanhoMaximoArquivo {
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("O arquivo passa do limite de %d MB.", tamanhoMaximoArquivo>>20)))
 				continue
 			}
 
-			// Adiciona trabalho à fila
-			workQueue.AddJob(ConversionJob{
-				ChatID:      msg.Chat.ID,
-				FileID:      fileID,
-				FileName:    fileName,
-				DownloadURL: file.Link(token),
+			// Adiciona trabalho à fila; o link de download é obtido quando o
+			// trabalho começar
+			enfileirar(bot, workQueue, ConversionJob{
+				Canal:     canalTelegram,
+				ChatID:    msg.Chat.ID,
+				FileID:    fileID,
+				FileName:  fileName,
+				FileSize:  fileSize,
+				Conversor: string(state.GetUserDatabaseChoice(msg.Chat.ID)),
+				Simular:   state.GetUserSimulacao(msg.Chat.ID),
 			})
 		}
 	}
 }
+
+// prefixoDump inicia os dados do botão que escolhe um dos dumps do arquivo,
+// seguido da posição do dump na lista (os nomes podem passar do limite de 64
+// bytes do Telegram)
+const prefixoDump = "dump:"
+
+// escolherDumpPendente enfileira de novo o trabalho que aguardava a escolha
+// do dump, agora com o dump da posição i
+func escolherDumpPendente(bot *tgbotapi.BotAPI, wq *WorkQueue, chatID int64, i string) {
+	dumpsPendentes.Lock()
+	job, ok := dumpsPendentes.jobs[chatID]
+	nomes := dumpsPendentes.nomes[chatID]
+	indice, err := strconv.Atoi(i)
+	if ok && err == nil && indice >= 0 && indice < len(nomes) {
+		delete(dumpsPendentes.jobs, chatID)
+		delete(dumpsPendentes.nomes, chatID)
+	} else {
+		ok = false
+	}
+	dumpsPendentes.Unlock()
+
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(chatID, "Nenhuma escolha de dump pendente. Envie o arquivo novamente."))
+		return
+	}
+	job.Membro = nomes[indice]
+	enfileirar(bot, wq, job)
+}
+
+// prefixoCancelar inicia os dados do botão que cancela um trabalho
+const prefixoCancelar = "cancelar:"
+
+// tecladoCancelar monta o botão que cancela o trabalho id
+func tecladoCancelar(id string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Cancelar", prefixoCancelar+id),
+		),
+	)
+}
+
+// descricaoEstado é como cada estado da fila aparece para o usuário
+var descricaoEstado = map[fila.Estado]string{
+	fila.NaFila:      "na fila",
+	fila.Processando: "em processamento",
+	fila.Concluido:   "concluída",
+	fila.Falhou:      "falhou",
+	fila.Cancelado:   "cancelada",
+}
+
+// maxEncerradosStatus limita quantas conversões já encerradas o /status mostra
+const maxEncerradosStatus = 3
+
+// donoChat identifica na fila os trabalhos enviados pelo chat
+func donoChat(chatID int64) string {
+	return ConversionJob{Canal: canalTelegram, ChatID: chatID}.Dono()
+}
+
+// trabalhosPendentes lista os trabalhos do chat que ainda não terminaram
+func trabalhosPendentes(workQueue *WorkQueue, chatID int64) []fila.Registro {
+	var pendentes []fila.Registro
+	for _, r := range workQueue.Listar(donoChat(chatID)) {
+		if r.Estado == fila.NaFila || r.Estado == fila.Processando {
+			pendentes = append(pendentes, r)
+		}
+	}
+	return pendentes
+}
+
+// enviarStatus lista as conversões pendentes do chat, com um botão para
+// cancelar cada uma, e as últimas já encerradas
+func enviarStatus(bot *tgbotapi.BotAPI, workQueue *WorkQueue, chatID int64, titulo string) {
+	registros := workQueue.Listar(donoChat(chatID))
+
+	var pendentes, encerrados []string
+	var botoes [][]tgbotapi.InlineKeyboardButton
+	for _, r := range registros {
+		var job ConversionJob
+		json.Unmarshal(r.Dados, &job)
+		linha := fmt.Sprintf("- %s: %s", job.FileName, descricaoEstado[r.Estado])
+
+		switch r.Estado {
+		case fila.NaFila, fila.Processando:
+			if posicao := workQueue.Posicao(r.ID); posicao > 0 {
+				linha += fmt.Sprintf(" (posição %d)", posicao)
+			}
+			pendentes = append(pendentes, linha)
+			botoes = append(botoes, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Cancelar "+job.FileName, prefixoCancelar+r.ID),
+			))
+		default:
+			if r.Erro != "" {
+				linha += ": " + r.Erro
+			}
+			encerrados = append(encerrados, linha)
+		}
+	}
+	if len(encerrados) > maxEncerradosStatus {
+		encerrados = encerrados[len(encerrados)-maxEncerradosStatus:]
+	}
+
+	if len(pendentes) == 0 && len(encerrados) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Você não tem conversões na fila."))
+		return
+	}
+
+	var sb strings.Builder
+	if titulo != "" {
+		sb.WriteString(titulo + "\n\n")
+	}
+	if len(pendentes) > 0 {
+		sb.WriteString("Conversões pendentes:\n" + strings.Join(pendentes, "\n") + "\n")
+	} else {
+		sb.WriteString("Nenhuma conversão pendente.\n")
+	}
+	if len(encerrados) > 0 {
+		sb.WriteString("\nÚltimas conversões:\n" + strings.Join(encerrados, "\n") + "\n")
+	}
+
+	reply := tgbotapi.NewMessage(chatID, sb.String())
+	if len(botoes) > 0 {
+		reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(botoes...)
+	}
+	bot.Send(reply)
+}
+
+// cancelarTrabalho cancela um trabalho do chat: tira da fila se ainda
+// aguarda ou interrompe o processamento
+func cancelarTrabalho(bot *tgbotapi.BotAPI, workQueue *WorkQueue, chatID int64, id string) {
+	estado, err := workQueue.Cancelar(id, donoChat(chatID))
+	switch {
+	case err != nil && estado != "":
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Essa conversão já terminou (%s).", descricaoEstado[estado])))
+	case err != nil:
+		bot.Send(tgbotapi.NewMessage(chatID, "Conversão não encontrada."))
+	case estado == fila.Processando:
+		bot.Send(tgbotapi.NewMessage(chatID, "Cancelando a conversão em andamento..."))
+	default:
+		bot.Send(tgbotapi.NewMessage(chatID, "Conversão retirada da fila."))
+	}
+}
+
+// enfileirar coloca o trabalho do chat na fila e informa a posição dele
+func enfileirar(bot *tgbotapi.BotAPI, workQueue *WorkQueue, job ConversionJob) {
+	posicao, err := workQueue.AddJob(job)
+	if err != nil {
+		log.Printf("Erro ao enfileirar o arquivo do chat %d: %v", job.ChatID, err)
+		bot.Send(tgbotapi.NewMessage(job.ChatID, "Erro ao colocar o arquivo na fila. Tente novamente."))
+		return
+	}
+	if posicao > 0 {
+		bot.Send(tgbotapi.NewMessage(job.ChatID, fmt.Sprintf("Seu arquivo está na fila de processamento (posição %d). Aguarde...", posicao)))
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Notificador entrega a quem pediu a conversão as mensagens e o resultado de
+// um trabalho. Cada canal de entrada (bot, API HTTP) tem o seu.
+type Notificador interface {
+	// Iniciado avisa que o trabalho saiu da fila e começou a ser processado
+	Iniciado()
+	// Progresso mostra a etapa atual e o andamento dela (detalhe pode ser
+	// vazio). Pode ser chamado com frequência; cabe ao notificador espaçar
+	// as atualizações.
+	Progresso(etapa, detalhe string)
+	// Mensagem envia um aviso intermediário
+	Mensagem(texto string)
+	// Falha encerra o trabalho com erro
+	Falha(texto string)
+	// Simulacao encerra um trabalho simulado com o relatório da simulação
+	Simulacao(job ConversionJob, texto string)
+	// EscolherDump encerra um trabalho cujo arquivo tem vários dumps,
+	// pedindo ao usuário que escolha qual converter
+	EscolherDump(job ConversionJob, dumps []string)
+	// Resultado entrega o arquivo convertido e encerra o trabalho
+	Resultado(arquivo string) error
+}
+
+// intervaloEdicao espaça as edições da mensagem de status dentro de uma
+// mesma etapa, para não esbarrar no limite de chamadas do Telegram
+const intervaloEdicao = 3 * time.Second
+
+// tamanhoMaximoMensagem é o maior texto, em caracteres, que o Telegram aceita
+// numa mensagem
+const tamanhoMaximoMensagem = 4096
+
+// notificadorTelegram responde no chat do usuário que enviou o arquivo. O
+// andamento vai numa única mensagem de status, editada a cada etapa e com
+// um botão para cancelar enquanto o trabalho não termina.
+type notificadorTelegram struct {
+	bot    *tgbotapi.BotAPI
+	chatID int64
+	jobID  string
+
+	mensagemStatus int // id da mensagem de status; 0 enquanto não enviada
+	etapa          string
+	ultimaEdicao   time.Time
+}
+
+func (n *notificadorTelegram) Iniciado() {
+	n.status("Processando seu arquivo...")
+}
+
+func (n *notificadorTelegram) Progresso(etapa, detalhe string) {
+	if etapa == n.etapa && time.Since(n.ultimaEdicao) < intervaloEdicao {
+		return
+	}
+	n.etapa = etapa
+	n.ultimaEdicao = time.Now()
+
+	texto := etapa + "..."
+	if detalhe != "" {
+		texto += "\n" + detalhe
+	}
+	n.status(texto)
+}
+
+// status envia a mensagem de status ou, se já enviada, edita o texto dela,
+// mantendo o botão de cancelar
+func (n *notificadorTelegram) status(texto string) {
+	teclado := tecladoCancelar(n.jobID)
+	if n.mensagemStatus == 0 {
+		msg := tgbotapi.NewMessage(n.chatID, texto)
+		msg.ReplyMarkup = teclado
+		if m, err := n.bot.Send(msg); err == nil {
+			n.mensagemStatus = m.MessageID
+		}
+		return
+	}
+	n.bot.Send(tgbotapi.NewEditMessageTextAndMarkup(n.chatID, n.mensagemStatus, texto, teclado))
+}
+
+// statusFinal grava o texto final da mensagem de status, já sem o botão
+func (n *notificadorTelegram) statusFinal(texto string) {
+	if n.mensagemStatus == 0 {
+		n.Mensagem(texto)
+		return
+	}
+	n.bot.Send(tgbotapi.NewEditMessageText(n.chatID, n.mensagemStatus, texto))
+}
+
+// Mensagem envia o texto, dividido em várias mensagens quando passa do
+// limite do Telegram
+func (n *notificadorTelegram) Mensagem(texto string) {
+	for _, trecho := range dividirMensagem(texto, tamanhoMaximoMensagem) {
+		n.bot.Send(tgbotapi.NewMessage(n.chatID, trecho))
+	}
+}
+
+func (n *notificadorTelegram) Falha(texto string) {
+	n.statusFinal("Conversão interrompida.")
+	n.Mensagem(texto)
+}
+
+// Simulacao envia o relatório com o botão para prosseguir e guarda o
+// trabalho até o usuário confirmar
+func (n *notificadorTelegram) Simulacao(job ConversionJob, texto string) {
+	// O trabalho confirmado entra na fila como um novo trabalho
+	pendente := job
+	pendente.ID = ""
+	pendente.Simular = false
+	simulacoesPendentes.Lock()
+	simulacoesPendentes.jobs[n.chatID] = pendente
+	simulacoesPendentes.Unlock()
+
+	n.statusFinal("Simulação concluída.")
+	reply := tgbotapi.NewMessage(n.chatID, texto)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Prosseguir com a conversão", "prosseguir"),
+		),
+	)
+	n.bot.Send(reply)
+}
+
+// EscolherDump envia um botão por dump e guarda o trabalho até o usuário
+// escolher
+func (n *notificadorTelegram) EscolherDump(job ConversionJob, dumps []string) {
+	// O trabalho com o dump escolhido entra na fila como um novo trabalho
+	pendente := job
+	pendente.ID = ""
+	dumpsPendentes.Lock()
+	dumpsPendentes.jobs[n.chatID] = pendente
+	dumpsPendentes.nomes[n.chatID] = dumps
+	dumpsPendentes.Unlock()
+
+	var linhas [][]tgbotapi.InlineKeyboardButton
+	for i, nome := range dumps {
+		linhas = append(linhas, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(nome, fmt.Sprintf("%s%d", prefixoDump, i)),
+		))
+	}
+
+	n.statusFinal("Aguardando a escolha do dump.")
+	reply := tgbotapi.NewMessage(n.chatID, "O arquivo tem mais de um dump SQL. Escolha qual converter:")
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(linhas...)
+	n.bot.Send(reply)
+}
+
+// Resultado envia o arquivo convertido, compactado e dividido em partes
+// conforme o limite de envio do Telegram, seguido do arquivo .sha256 e do
+// manifesto, para o usuário conferir e juntar as partes
+func (n *notificadorTelegram) Resultado(arquivo string) error {
+	n.Progresso("Preparando o arquivo para envio", "")
+	e, err := prepararEntrega(arquivo, compactacaoResultado, tamanhoMaximoEnvio)
+	if err != nil {
+		n.Falha(fmt.Sprintf("Erro ao preparar o backup para envio: %v", err))
+		return err
+	}
+	// As partes ficam na área de trabalho do trabalho, que também é removida
+	defer e.remover()
+
+	for i, parte := range e.Partes {
+		doc := tgbotapi.NewDocument(n.chatID, tgbotapi.FilePath(parte.Arquivo))
+		doc.Caption = "Backup do banco de dados"
+		detalhe := ""
+		if len(e.Partes) > 1 {
+			doc.Caption = fmt.Sprintf("Backup do banco de dados (parte %d de %d)", i+1, len(e.Partes))
+			detalhe = fmt.Sprintf("Parte %d de %d", i+1, len(e.Partes))
+		}
+		n.Progresso("Enviando o arquivo convertido", detalhe)
+
+		if _, err := n.bot.Send(doc); err != nil {
+			n.Falha(fmt.Sprintf("Erro ao enviar o backup: %v", err))
+			return err
+		}
+	}
+
+	somas, err := e.gravarSomas()
+	if err != nil {
+		n.Falha(fmt.Sprintf("Erro ao preparar o backup para envio: %v", err))
+		return err
+	}
+	defer os.Remove(somas)
+	doc := tgbotapi.NewDocument(n.chatID, tgbotapi.FilePath(somas))
+	doc.Caption = "SHA-256 para conferência"
+	if _, err := n.bot.Send(doc); err != nil {
+		n.Falha(fmt.Sprintf("Erro ao enviar o backup: %v", err))
+		return err
+	}
+
+	n.Mensagem(e.manifesto())
+	n.statusFinal("Conversão concluída. Backup enviado com sucesso!")
+	return nil
+}
+
+// dividirMensagem quebra o texto em trechos de até limite caracteres,
+// preferindo cortar em quebras de linha
+func dividirMensagem(texto string, limite int) []string {
+	var trechos []string
+	for utf8.RuneCountInString(texto) > limite {
+		// Posição em bytes do primeiro caractere que passa do limite
+		corte := 0
+		for i := 0; i < limite; i++ {
+			_, tamanho := utf8.DecodeRuneInString(texto[corte:])
+			corte += tamanho
+		}
+		if i := strings.LastIndexByte(texto[:corte], '\n'); i > 0 {
+			trechos = append(trechos, texto[:i])
+			texto = texto[i+1:]
+			continue
+		}
+		trechos = append(trechos, texto[:corte])
+		texto = texto[corte:]
+	}
+	return append(trechos, texto)
+}
+
+// notificadorFila repassa tudo ao notificador do canal e guarda a falha, se
+// houver, para registrar o estado final do trabalho na fila
+type notificadorFila struct {
+	Notificador
+	falha string
+}
+
+func (n *notificadorFila) Falha(texto string) {
+	n.falha = texto
+	n.Notificador.Falha(texto)
+}
+
+// Resultado registra como falha um resultado que não chegou ao usuário; o
+// notificador do canal já o avisou do erro
+func (n *notificadorFila) Resultado(arquivo string) error {
+	err := n.Notificador.Resultado(arquivo)
+	if err != nil && n.falha == "" {
+		n.falha = err.Error()
+	}
+	return err
+}
+
+// notificadorSemBot atende os trabalhos do Telegram retomados da fila quando
+// o processo roda sem BOT_TOKEN: não há como falar com o usuário, então as
+// mensagens vão para o log e o resultado não pode ser entregue
+type notificadorSemBot struct {
+	job ConversionJob
+}
+
+func (n notificadorSemBot) Iniciado()                       {}
+func (n notificadorSemBot) Progresso(etapa, detalhe string) {}
+
+func (n notificadorSemBot) Mensagem(texto string) {
+	log.Printf("Trabalho %s (chat %d): %s", n.job.ID, n.job.ChatID, texto)
+}
+
+func (n notificadorSemBot) Falha(texto string) {
+	log.Printf("Trabalho %s (chat %d) falhou: %s", n.job.ID, n.job.ChatID, texto)
+}
+
+func (n notificadorSemBot) Simulacao(job ConversionJob, texto string) {
+	n.Mensagem("simulação concluída sem o bot para entregá-la")
+}
+
+func (n notificadorSemBot) EscolherDump(job ConversionJob, dumps []string) {
+	n.Mensagem("o arquivo tem vários dumps e não há bot para perguntar qual converter")
+}
+
+func (n notificadorSemBot) Resultado(arquivo string) error {
+	return errors.New("bot do Telegram não está ativo para entregar o resultado")
+}
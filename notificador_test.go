@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDividirMensagem(t *testing.T) {
+	casos := []struct {
+		nome   string
+		texto  string
+		limite int
+		partes int
+	}{
+		{"vazia", "", 10, 1},
+		{"dentro do limite", "abcdefghij", 10, 1},
+		{"sem quebras de linha", strings.Repeat("a", 25), 10, 3},
+		{"corta na quebra de linha", "abc\ndefgh\nijklmn", 10, 2},
+		{"acentos no corte", strings.Repeat("é", 25), 10, 3},
+		{"emojis no corte", strings.Repeat("a🙂", 13), 10, 3},
+		{"linhas acentuadas", strings.Repeat("ação\n", 30), 12, 15},
+		{"limite do Telegram", strings.Repeat("Arquivo convertido ✓\n", 500), tamanhoMaximoMensagem, 3},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			partes := dividirMensagem(c.texto, c.limite)
+			if len(partes) != c.partes {
+				t.Errorf("%d partes, esperado %d", len(partes), c.partes)
+			}
+
+			resto := c.texto
+			for i, p := range partes {
+				if !utf8.ValidString(p) {
+					t.Errorf("parte %d partiu um caractere: %q", i, p)
+				}
+				if n := utf8.RuneCountInString(p); n > c.limite {
+					t.Errorf("parte %d com %d caracteres, acima do limite %d", i, n, c.limite)
+				}
+				// As partes reproduzem o texto, tirando as quebras de linha
+				// onde ele foi cortado
+				if !strings.HasPrefix(resto, p) {
+					t.Fatalf("parte %d (%q) não continua o texto", i, p)
+				}
+				resto = resto[len(p):]
+				if i < len(partes)-1 {
+					resto = strings.TrimPrefix(resto, "\n")
+				}
+			}
+			if resto != "" {
+				t.Errorf("texto não reproduzido pelas partes; sobrou %q", resto)
+			}
+		})
+	}
+}